@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/mikaelstaldal/gopw/pw"
+)
+
+// browseItem is an entry in the browse list.
+type browseItem struct {
+	entry pw.PasswordEntry
+}
+
+func (i browseItem) Title() string       { return i.entry.Name }
+func (i browseItem) Description() string { return i.entry.Username }
+func (i browseItem) FilterValue() string { return i.entry.Name + " " + i.entry.Username }
+
+var (
+	copyPasswordKey = key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "copy password"))
+	copyUsernameKey = key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "copy username"))
+	exitKey         = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "exit"))
+)
+
+// browseModel is a terminal UI for browsing the entries, which are decrypted once on startup.
+type browseModel struct {
+	list list.Model
+	out  passwordOutput
+}
+
+func newBrowseModel(entries []pw.PasswordEntry, out passwordOutput) browseModel {
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = browseItem{entry}
+	}
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Passwords"
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{copyPasswordKey, copyUsernameKey, exitKey}
+	}
+	return browseModel{list: l, out: out}
+}
+
+func (m browseModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch {
+		case key.Matches(msg, exitKey) && m.list.FilterState() == list.Unfiltered:
+			return m, tea.Quit
+
+		case key.Matches(msg, copyPasswordKey):
+			return m, m.copyField("password", func(entry pw.PasswordEntry) string { return entry.Password })
+
+		case key.Matches(msg, copyUsernameKey):
+			return m, m.copyField("username", func(entry pw.PasswordEntry) string { return entry.Username })
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// copyField copies a field of the selected entry to the clipboard.
+func (m browseModel) copyField(field string, value func(entry pw.PasswordEntry) string) tea.Cmd {
+	item, ok := m.list.SelectedItem().(browseItem)
+	if !ok {
+		return nil
+	}
+	entry := item.entry
+	if err := pw.Unseal(&entry); err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	if err := m.out.copy(value(entry)); err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	return m.list.NewStatusMessage(fmt.Sprintf("Copied %s for %s", field, item.entry.Name))
+}
+
+func (m browseModel) View() string {
+	return m.list.View()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/atotto/clipboard"
+)
+
+const (
+	selectionClipboard = "clipboard"
+	selectionPrimary   = "primary"
+)
+
+// primaryCommand is a command for copying to or pasting from the primary selection.
+type primaryCommand struct {
+	copyArgs  []string
+	pasteArgs []string
+}
+
+// primaryCommands returns the available commands for the primary selection, in order of preference.
+func primaryCommands() ([]primaryCommand, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("the primary selection is only supported on Linux")
+	}
+
+	var commands []primaryCommand
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, primaryCommand{
+			[]string{"wl-copy", "--primary"},
+			[]string{"wl-paste", "--primary", "--no-newline"},
+		})
+	}
+	commands = append(commands,
+		primaryCommand{
+			[]string{"xclip", "-in", "-selection", "primary"},
+			[]string{"xclip", "-out", "-selection", "primary"},
+		},
+		primaryCommand{
+			[]string{"xsel", "--input", "--primary"},
+			[]string{"xsel", "--output", "--primary"},
+		})
+
+	var available []primaryCommand
+	for _, command := range commands {
+		if _, err := exec.LookPath(command.copyArgs[0]); err == nil {
+			available = append(available, command)
+		}
+	}
+	if len(available) == 0 {
+		return nil, errors.New("no wl-copy, xclip or xsel found in PATH, which is required for the primary selection")
+	}
+	return available, nil
+}
+
+// isWSL reports whether running on Windows Subsystem for Linux, where the Windows clipboard is used.
+var isWSL = sync.OnceValue(func() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	version, err := os.ReadFile("/proc/version")
+	return err == nil && strings.Contains(strings.ToLower(string(version)), "microsoft")
+})
+
+// copyToWindowsClipboard copies text to the Windows clipboard from WSL with clip.exe.
+func copyToWindowsClipboard(text string) error {
+	// clip.exe reads text in the console code page, unless it is UTF-16 with a byte order mark
+	encoded := []byte{0xff, 0xfe}
+	for _, c := range utf16.Encode([]rune(text)) {
+		encoded = append(encoded, byte(c), byte(c>>8))
+	}
+	cmd := exec.Command("clip.exe")
+	cmd.Stdin = bytes.NewReader(encoded)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to execute clip.exe: %w", err)
+	}
+	return nil
+}
+
+// readWindowsClipboard returns the contents of the Windows clipboard from WSL with PowerShell.
+func readWindowsClipboard() (string, error) {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		"[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw")
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to execute powershell.exe: %w", err)
+	}
+	return strings.TrimSuffix(string(output), "\r\n"), nil
+}
+
+// copyToClipboard copies text to the given X11 selection. The clipboard library
+// only supports the CLIPBOARD selection, so PRIMARY is handled by running
+// wl-copy, xclip or xsel directly. On WSL, the Windows clipboard is used instead.
+func copyToClipboard(text string, selection string) error {
+	if selection != selectionPrimary {
+		if isWSL() {
+			return copyToWindowsClipboard(text)
+		}
+		return clipboard.WriteAll(text)
+	}
+
+	commands, err := primaryCommands()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(commands[0].copyArgs[0], commands[0].copyArgs[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to execute %s: %w", commands[0].copyArgs[0], err)
+	}
+	return nil
+}
+
+// readClipboard returns the contents of the given X11 selection.
+func readClipboard(selection string) (string, error) {
+	if selection != selectionPrimary {
+		if isWSL() {
+			return readWindowsClipboard()
+		}
+		return clipboard.ReadAll()
+	}
+
+	commands, err := primaryCommands()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(commands[0].pasteArgs[0], commands[0].pasteArgs[1:]...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to execute %s: %w", commands[0].pasteArgs[0], err)
+	}
+	return string(output), nil
+}
+
+// scheduleClipboardClear starts a detached process, which clears the selection after the delay
+// unless it no longer contains text. The process is given text on stdin, to not expose it in the process list.
+func scheduleClipboardClear(text string, selection string, delay time.Duration) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, "-selection", selection, "clipboard-clear", "-after", delay.String())
+	cmd.SysProcAttr = detachedProcess()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start clipboard clearing process: %w", err)
+	}
+	_, err = io.WriteString(stdin, text)
+	_ = stdin.Close()
+	_ = cmd.Process.Release()
+	return err
+}
+
+// clipboardClearCmd is the hidden command run by scheduleClipboardClear.
+func clipboardClearCmd(selection string, delay time.Duration) {
+	text, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	time.Sleep(delay)
+
+	current, err := readClipboard(selection)
+	if err != nil || !bytes.Equal([]byte(current), text) {
+		os.Exit(1)
+	}
+	if err := copyToClipboard("", selection); err != nil {
+		os.Exit(1)
+	}
+}
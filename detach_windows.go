@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+const detachedProcessFlag = 0x00000008 // DETACHED_PROCESS
+
+// detachedProcess returns the attributes to start a process without a console, so that it survives this process.
+func detachedProcess() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcessFlag | syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -2,12 +2,29 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
 
 	"github.com/mikaelstaldal/gopw/pw"
 )
@@ -16,19 +33,92 @@ func main() {
 	filename := flag.String("file", filepath.Join(os.Getenv("HOME"), "pw.scrypt"), "The encrypted password file")
 	passwordLength := flag.Int("password-length", 16, "Password length")
 	passwordChars := flag.String("password-charset", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-", "Password charset")
+	charsetFile := flag.String("charset-file", "", "Read the password charset from this file, instead of -password-charset")
+	urlSafe := flag.Bool("url-safe", false, "Only use characters from the password charset which need no escaping in URLs")
+	shellSafe := flag.Bool("shell-safe", false, "Only use characters from the password charset which need no quoting in a shell")
+	exclude := flag.String("exclude", "", "Characters to remove from the password charset")
+	verbose := flag.Bool("verbose", false, "Print the length, charset size and entropy of generated passwords to stderr")
+	outFd := flag.Int("out-fd", -1, "Write the password to this file descriptor instead of the clipboard")
+	keyfile := flag.String("keyfile", "", "Derive the passphrase from this key file, instead of typing it")
+	keyfilePassphrase := flag.Bool("keyfile-passphrase", false, "Require a typed passphrase in addition to the key file")
+	passphraseCommand := flag.String("passphrase-command", "", "A command printing the passphrase on the first line of its output, instead of typing it")
+	backup := flag.Bool("backup", true, "Keep the previous version of the passwords file as a backup when writing it")
+	compress := flag.Bool("compress", false, "Compress the passwords file before encrypting it")
+	fileMode := flag.String("file-mode", envOrDefault("GOPW_FILE_MODE", "0600"), "The permission mode of the password file, in octal (env GOPW_FILE_MODE)")
+	dir := flag.String("dir", "", "A directory of encrypted password files named by tag, to use instead of -file (supports get, list and add)")
+	mirror := flag.String("mirror", os.Getenv("GOPW_MIRROR"), "A mirror file updated every time the passwords file is written, and read if it cannot be read (env GOPW_MIRROR)")
+	split := flag.String("split", "", "Two comma-separated share files to split the encrypted passwords file into, both are needed to read it")
+	pruneExpired := flag.Bool("prune-expired", false, "Remove expired passwords from the passwords file when reading it")
+	armor := flag.Bool("armor", false, "Write the encrypted passwords file base64 encoded as text, e.g. for committing to git")
+	seal := flag.Bool("seal", false, "Create new password files with each password encrypted individually, and names in plaintext")
+	verifyWrites := flag.Bool("verify-writes", false, "Decrypt the password file after writing it and check its content")
+	strictPerms := flag.Bool("strict-perms", false, "Refuse to read the password file if it is accessible by group or others")
+	clearAfter := flag.Duration("clear-after", 0, "Clear the clipboard after this time, unless it has been changed (default never)")
+	selection := flag.String("selection", selectionClipboard, "The X11 selection to copy the password to on Linux: clipboard or primary")
+	recentFile := flag.String("recent-file", os.Getenv("GOPW_RECENT_FILE"), "A file tracking which passwords are used, for the recent command, in plaintext, none if empty (env GOPW_RECENT_FILE)")
+	timeout := flag.Duration("timeout", 0, "Fail if the command takes longer than this, killing scrypt if running (default no timeout)")
+	logVerbose := flag.Bool("v", false, "Log operations and timings to stderr")
+	logDebug := flag.Bool("vv", false, "Log operations, timings and commands run to stderr")
 	flag.Parse()
 	args := flag.Args()
 	if len(args) < 1 {
 		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		_, _ = fmt.Fprintln(os.Stderr)
 		_, _ = fmt.Fprintf(os.Stderr, `Commands:
-  init      Create an empty encrypted passwords file
-  get       Lookup a password
-  list      List all passwords
-  add       Add a password
-  update    Update a password
-  remove    Remove a password
-  generate  Generates a password without storing it
+  init              Create an empty encrypted passwords file
+  get               Lookup a password
+  get-many          Print the passwords of several entries, read in one go, without using the clipboard
+  list              List all passwords
+  add               Add a password
+  add-temp          Add a password which expires after a while
+  add-account       Add another account with a generated password to an existing entry
+  update            Update a password
+  remove            Remove one or more passwords
+  generate          Generates a password without storing it
+  add-recovery      Add a recovery phrase to a password, typed or generated
+  set-totp          Set the TOTP secret of a password, read from stdin
+  open              Copy a password and open its URL in the browser
+  set-url           Set the URL of a password
+  login             Copy a password, and then the current TOTP code after pressing Enter
+  otpauth-uri       Print the otpauth:// URI for the TOTP secret of a password
+  migrate           Migrate a passwords file encrypted with another backend into a new file
+  recent            Get the Nth most used password, 1 for the most used
+  by-username       List passwords with a username, matching email addresses case-insensitively
+  older-than        List passwords last changed before a date, like 2023-01-01, and those of unknown age
+  migrate-format    Rewrite an old passwords file in the current format version
+  add-sshkey        Add an SSH private key from a file
+  get-sshkey        Write an SSH private key to a file
+  weak              List passwords with an estimated strength below a minimum
+  edit              Edit all passwords as JSON in $VISUAL or $EDITOR, in a plaintext temporary file
+  selftest          Check the random number generator and measure password generation throughput
+  score             Show a health score from 0 to 100 for the passwords, with what lowers it
+  export            Export one password in plaintext as JSON
+  export-jsonl      Export all passwords in plaintext as JSON Lines
+  destroy           Overwrite and remove the passwords file and its backups, mirror and shares
+  vault-note        Show the note about the passwords file, or set it if given (empty to remove)
+  info              Show the path, size, modification time and backend of the passwords file
+  relocate          Move the passwords file to a new path, checking that it can be decrypted there
+  derive            Derive a password for a site from a master secret, without storing it
+  normalize-emails  List entries with email usernames having uppercase domain, lowercase with -apply
+  inspect           Show the character classes, length and strength of a password without revealing it
+  audit-policy      List passwords violating their stored generation policy
+  push              Copy a password to a file on a remote host with ssh
+  undo              Restore the most recent backup, reverting the last change
+  cleanup           Remove all but the most recent backups
+  export-env        Export passwords in plaintext in .env format
+  browse            Browse the passwords interactively
+  diff              Compare with another encrypted passwords file
+  verify-backup     Check that a plaintext JSON backup, like from export-jsonl, matches the passwords file
+  alias             Add or remove an alias for a password
+  tag               Add or remove a tag for a password
+  tag-all           Add a tag to all passwords with name or username containing a query
+  tags              List all tags with the number of passwords having each, most used first
+  verify-password   Check if a typed password matches a stored password
+  agent             Start an agent caching the passphrase, set GOPW_AGENT_SOCK to use it
+  lock              Make the agent forget the cached passphrase
+  recover           Last resort: salvage entries from a corrupted passwords file into a new file
+  import            Import passwords from JSON, as written by export-jsonl, or CSV with name, username, password and url columns
+  import-keepass    Import passwords from a KeePass CSV export, skipping names which already exist
 `)
 		_, _ = fmt.Fprintln(os.Stderr)
 		_, _ = fmt.Fprintln(os.Stderr, "Options:")
@@ -37,141 +127,2390 @@ func main() {
 	}
 	command := args[0]
 
+	if *selection != selectionClipboard && *selection != selectionPrimary {
+		_, _ = fmt.Fprintf(os.Stderr, "Invalid selection: %s\n", *selection)
+		os.Exit(1)
+	}
+	mode, err := strconv.ParseUint(*fileMode, 8, 32)
+	if err != nil || mode > 0777 || mode&0400 == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Invalid file mode, must be octal and readable by the owner: %s\n", *fileMode)
+		os.Exit(1)
+	}
+	pw.FileMode = os.FileMode(mode)
+	pw.Compress = *compress
+	pw.StrictPerms = *strictPerms
+	pw.VerifyWrites = *verifyWrites
+	pw.Seal = *seal
+	pw.Armor = *armor
+	pw.PruneExpired = *pruneExpired
+	logLevel := slog.LevelWarn
+	if *logVerbose {
+		logLevel = slog.LevelInfo
+	}
+	if *logDebug {
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	if err := setScryptLimits(os.Getenv("GOPW_SCRYPT_MAXMEM"), os.Getenv("GOPW_SCRYPT_MAXTIME")); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *mirror != "" {
+		pw.Mirrors[*filename] = *mirror
+	}
+	if *split != "" {
+		shares := strings.Split(*split, ",")
+		if len(shares) != 2 || shares[0] == "" || shares[1] == "" || shares[0] == shares[1] {
+			_, _ = fmt.Fprintln(os.Stderr, "Error: -split requires two different files separated by a comma")
+			os.Exit(1)
+		}
+		if *mirror != "" {
+			_, _ = fmt.Fprintln(os.Stderr, "Error: -split cannot be used with -mirror")
+			os.Exit(1)
+		}
+		pw.Splits[*filename] = [2]string{shares[0], shares[1]}
+	}
+	pw.Backup = *backup
+	pw.RecentFile = *recentFile
+
+	charset := *passwordChars
+	if *charsetFile != "" {
+		content, err := os.ReadFile(*charsetFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Unable to read charset file: %v\n", err)
+			os.Exit(1)
+		}
+		charset = strings.TrimSpace(string(content))
+		if charset == "" {
+			_, _ = fmt.Fprintf(os.Stderr, "Charset file %s is empty\n", *charsetFile)
+			os.Exit(1)
+		}
+	}
+	if *urlSafe {
+		charset = pw.FilterCharset(charset, pw.URLSafeChars)
+	}
+	if *shellSafe {
+		charset = pw.FilterCharset(charset, pw.ShellSafeChars)
+	}
+	charset = pw.ExcludeChars(charset, *exclude)
+	if charset == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "No characters left in the password charset")
+		os.Exit(1)
+	}
+
+	if *timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		pw.Context = ctx
+		// Only the context is canceled, so that the command fails through its error path and cleans up after itself
+		context.AfterFunc(ctx, func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: timed out after %v\n", *timeout)
+			}
+		})
+	}
+
+	pw.PreWriteHook = os.Getenv("GOPW_PRE_WRITE_HOOK")
+	pw.PostWriteHook = os.Getenv("GOPW_POST_WRITE_HOOK")
+	if *keyfile != "" {
+		if _, err := os.Stat(*keyfile); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Key file not found: %v\n", err)
+			os.Exit(1)
+		}
+		pw.Passphrase = sync.OnceValues(func() (string, error) {
+			passphrase := ""
+			if *keyfilePassphrase {
+				var err error
+				passphrase, err = readSecret("Passphrase: ")
+				if err != nil {
+					return "", fmt.Errorf("unable to read passphrase: %w", err)
+				}
+			}
+			return pw.KeyfilePassphrase(*keyfile, passphrase)
+		})
+	}
+	if *passphraseCommand != "" {
+		if *keyfile != "" {
+			_, _ = fmt.Fprintln(os.Stderr, "Error: -passphrase-command cannot be used with -keyfile")
+			os.Exit(1)
+		}
+		pw.Passphrase = sync.OnceValues(func() (string, error) {
+			return pw.CommandPassphrase(*passphraseCommand)
+		})
+	}
+	pw.AgentSocket = os.Getenv("GOPW_AGENT_SOCK")
+	pw.AgentPassphrase = readSecret
+
+	out := passwordOutput{fd: *outFd, selection: *selection, clearAfter: *clearAfter}
+	if *outFd >= 0 {
+		// Check the file descriptor before the passwords file is changed, so that a generated password is not lost
+		file, err := openOutFd(*outFd)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		out.file = file
+	}
+
+	if *dir != "" && command != "get" && command != "list" && command != "add" {
+		_, _ = fmt.Fprintf(os.Stderr, "Command %s is not supported with -dir\n", command)
+		os.Exit(1)
+	}
+
 	switch command {
 	case "init":
 		initCmd(*filename)
 
-	case "get":
-		if len(args) < 2 {
-			_, _ = fmt.Fprintln(os.Stderr, "Name required")
-			os.Exit(1)
+	case "get":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		field := fs.String("field", "password", "The field to copy: password, username, notes or recovery")
+		account := fs.String("account", "", "The username of the account, required if the entry has multiple accounts")
+		noNewline := fs.Bool("n", false, "Do not print a newline after the username")
+		quiet := fs.Bool("quiet", false, "Do not confirm copying to the clipboard")
+		combined := fs.Bool("combined", false, "Copy the username and password together, separated by -separator, for pasting both at once")
+		separator := fs.String("separator", "\t", "The separator between username and password with -combined")
+		id := fs.String("id", "", "Get the password with this ID instead of by name")
+		retries := fs.Int("retries", 0, "The number of times to prompt for the passphrase again if it is wrong")
+		cmdArgs := parseArgs(fs, args[1:])
+		pw.DecryptRetries = *retries
+		if *id != "" {
+			if len(cmdArgs) > 0 || *dir != "" {
+				_, _ = fmt.Fprintln(os.Stderr, "-id cannot be used with a name or -dir")
+				os.Exit(1)
+			}
+		} else if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		if *field != "password" && *field != "username" && *field != "notes" && *field != "recovery" {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid field: %s\n", *field)
+			os.Exit(1)
+		}
+		if *combined && *field != "password" {
+			_, _ = fmt.Fprintln(os.Stderr, "-combined cannot be used with -field")
+			os.Exit(1)
+		}
+		out.quiet = *quiet
+		switch {
+		case *dir != "":
+			getDirCmd(*dir, cmdArgs[0], *account, *field, *noNewline, *combined, *separator, out)
+		case *id != "":
+			getCmd(*filename, "", *id, *account, *field, *noNewline, *combined, *separator, out)
+		default:
+			getCmd(*filename, cmdArgs[0], "", *account, *field, *noNewline, *combined, *separator, out)
+		}
+
+	case "list":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		templateFile := fs.String("template-file", "", "Render all entries through a Go text/template file")
+		allowPasswords := fs.Bool("allow-passwords", false, "Allow the template to access passwords")
+		since := fs.String("since", "", "Only list passwords added or changed within this time, e.g. 7d or 24h")
+		width := fs.Int("width", 0, "Truncate lines to this width, with aligned columns (default the terminal width if a terminal, otherwise no limit)")
+		groupByTag := fs.Bool("group-by-tag", false, "List the passwords grouped under a header for each tag, colored on a terminal unless NO_COLOR is set")
+		parseArgs(fs, args[1:])
+		if !isFlagSetIn(fs, "width") {
+			*width = terminalWidth()
+		}
+		switch {
+		case *dir != "" && (*templateFile != "" || *since != ""):
+			_, _ = fmt.Fprintln(os.Stderr, "Options -template-file and -since are not supported with -dir")
+			os.Exit(1)
+		case *groupByTag && (*dir != "" || *templateFile != "" || *since != ""):
+			_, _ = fmt.Fprintln(os.Stderr, "Option -group-by-tag is not supported with -dir, -template-file or -since")
+			os.Exit(1)
+		case *groupByTag:
+			listByTagCmd(*filename, *width)
+		case *dir != "":
+			listDirCmd(*dir, *width)
+		case *templateFile != "":
+			listTemplateCmd(*filename, *templateFile, *allowPasswords)
+		case *since != "":
+			listSinceCmd(*filename, *since, *width)
+		default:
+			listCmd(*filename, *width)
+		}
+
+	case "add":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		policy := addPolicyFlags(fs)
+		siteProfile := fs.String("site-profile", "", "Check the generated password against the rules of a site profile in the config file")
+		tag := fs.String("tag", "", "A tag for the password, which selects the file with -dir")
+		quiet := fs.Bool("quiet", false, "Do not confirm copying to the clipboard")
+		hint := fs.Bool("hint", false, "Print a mnemonic hint for the password and store it in the notes, see generate -hint")
+		meter := fs.Bool("meter", false, "Print a strength meter bar for the password")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) == 1 && term.IsTerminal(int(os.Stdin.Fd())) {
+			username, err := readLine("Username: ")
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			cmdArgs = append(cmdArgs, username)
+		}
+		if len(cmdArgs) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
+			os.Exit(1)
+		}
+		profile := loadSiteProfile(*siteProfile)
+		out.quiet = *quiet
+		addCmd(*passwordLength, charset, policy.policy(*passwordLength, charset), profile, *verbose, *hint, *meter, out, *filename, *dir, *tag, cmdArgs[0], cmdArgs[1])
+
+	case "add-temp":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		ttl := fs.Duration("ttl", time.Hour, "Time until the password expires and is removed")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
+			os.Exit(1)
+		}
+		addTempCmd(*passwordLength, charset, *ttl, *verbose, out, *filename, cmdArgs[0], cmdArgs[1])
+
+	case "add-account":
+		cmdArgs := args[1:]
+		if len(cmdArgs) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
+			os.Exit(1)
+		}
+		addAccountCmd(*passwordLength, charset, *verbose, out, *filename, cmdArgs[0], cmdArgs[1])
+
+	case "update":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		policy := addPolicyFlags(fs)
+		showOld := fs.Bool("show-old", false, "Print the old password to stderr after updating")
+		raw := fs.Bool("raw", false, "Print only the old password with -show-old, without a label")
+		quiet := fs.Bool("quiet", false, "Do not confirm copying to the clipboard")
+		force := fs.Bool("force", false, "Update without asking for confirmation, required when stdin is not a terminal")
+		id := fs.String("id", "", "Update the password with this ID instead of by name")
+		cmdArgs := parseArgs(fs, args[1:])
+		out.quiet = *quiet
+		lengthSet := isFlagSet("password-length")
+		if *id != "" {
+			if len(cmdArgs) != 1 {
+				_, _ = fmt.Fprintln(os.Stderr, "Username required, and no name with -id")
+				os.Exit(1)
+			}
+			updateCmd(*passwordLength, lengthSet, charset, policy.policy(*passwordLength, charset), *verbose, *showOld, *raw, *force, out, *filename, "", *id, cmdArgs[0])
+		} else {
+			if len(cmdArgs) < 2 {
+				_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
+				os.Exit(1)
+			}
+			updateCmd(*passwordLength, lengthSet, charset, policy.policy(*passwordLength, charset), *verbose, *showOld, *raw, *force, out, *filename, cmdArgs[0], "", cmdArgs[1])
+		}
+
+	case "get-many":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		jsonOutput := fs.Bool("json", false, "Print the entries as a JSON array, instead of a line with name and password per entry")
+		names := parseArgs(fs, args[1:])
+		if len(names) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		getManyCmd(*filename, names, *jsonOutput)
+
+	case "remove":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		strict := fs.Bool("strict", false, "Remove nothing if any of the names is not found")
+		id := fs.String("id", "", "Remove the password with this ID instead of by name")
+		names := parseArgs(fs, args[1:])
+		if *id != "" {
+			if len(names) > 0 {
+				_, _ = fmt.Fprintln(os.Stderr, "-id cannot be used with a name")
+				os.Exit(1)
+			}
+			removeByIDCmd(*filename, *id)
+		} else if len(names) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		} else if len(names) == 1 {
+			removeCmd(*filename, names[0])
+		} else {
+			removeManyCmd(*filename, names, *strict)
+		}
+
+	case "generate":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		diceware := fs.Bool("diceware", false, "Generate a diceware passphrase instead, and print it with its entropy")
+		words := fs.Int("words", 6, "Number of words in a diceware passphrase, in a memorable password (default 2), or in a BIP-39 phrase (default 12)")
+		numeric := fs.Bool("numeric", false, "Generate digits only")
+		luhn := fs.Bool("luhn", false, "Generate digits with a Luhn check digit, like a credit card number")
+		length := fs.Int("length", 0, "Password length (default -password-length)")
+		require := fs.String("require", "", "Comma separated character classes the password must contain: lower, upper, digit, symbol")
+		noSequences := fs.Bool("no-sequences", false, "Avoid three or more sequential or repeated characters, like abc or aaa")
+		minBits := fs.Float64("min-bits", 0, "Make the password long enough to have at least this entropy in bits, instead of -length")
+		memorable := fs.Bool("memorable", false, "Generate a memorable password of capitalized words, a number and a symbol, and print its entropy")
+		noNumber := fs.Bool("no-number", false, "Leave out the number in a memorable password")
+		noSymbol := fs.Bool("no-symbol", false, "Leave out the symbol in a memorable password")
+		hint := fs.Bool("hint", false, "Also print a mnemonic hint of three words derived from the password, which does not reveal it")
+		bip39 := fs.Bool("bip39", false, "Generate a BIP-39 recovery phrase of -words, 12 (default), 15, 18, 21 or 24 words")
+		weights := fs.String("weights", "", "Comma separated weights of character classes, like lower=5,upper=2,digit=1, choosing classes by weight instead of -password-charset")
+		randomBytes := fs.Int("bytes", 0, "Generate this number of random bytes encoded with -encoding instead, bypassing the charset")
+		encoding := fs.String("encoding", "base64", "The encoding of random bytes with -bytes: base64 or hex")
+		meter := fs.Bool("meter", false, "Print a strength meter bar for a password generated from a charset")
+		layout := fs.String("layout", "", "Generate from an easy typing charset instead: numeric-top-row, qwerty-top-row, qwerty-home-row, left-hand, right-hand or phone-lower")
+		parseArgs(fs, args[1:])
+		var layoutChars string
+		if *layout != "" {
+			var err error
+			layoutChars, err = pw.LayoutCharset(*layout)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *minBits > 0 {
+			chars := charset
+			if *numeric || *luhn {
+				chars = "0123456789"
+			}
+			if *layout != "" {
+				chars = layoutChars
+			}
+			n, err := pw.LengthForEntropy(*minBits, chars)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			*length = n
+		}
+		if *length == 0 {
+			*length = *passwordLength
+		}
+		switch {
+		case isFlagSetIn(fs, "bytes"):
+			generateBytesCmd(*randomBytes, *encoding, out)
+		case *weights != "":
+			generateWeightedCmd(*length, *weights, out)
+		case *bip39:
+			wordCount := 12
+			if isFlagSetIn(fs, "words") {
+				wordCount = *words
+			}
+			bip39Cmd(wordCount, out)
+		case *diceware:
+			dicewareCmd(*words)
+		case *memorable:
+			wordCount := 2
+			if isFlagSetIn(fs, "words") {
+				wordCount = *words
+			}
+			memorableCmd(pw.MemorableOptions{Words: wordCount, Number: !*noNumber, Symbol: !*noSymbol}, out)
+		case *luhn:
+			luhnCmd(*length, out)
+		case *layout != "":
+			generateLayoutCmd(*length, layoutChars, charset, *hint, *meter, out)
+		case *numeric:
+			generateCmd(*length, "0123456789", *verbose, *hint, *meter, out)
+		case *require != "":
+			generateRequiringCmd(*length, charset, *require, out)
+		case *noSequences:
+			generateNoSequencesCmd(*length, charset, out)
+		default:
+			generateCmd(*length, charset, *verbose, *hint, *meter, out)
+		}
+
+	case "add-recovery":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		generate := fs.Bool("generate", false, "Generate a BIP-39 recovery phrase and output it, instead of typing one")
+		words := fs.Int("words", 12, "Number of words of a generated recovery phrase: 12, 15, 18, 21 or 24")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		addRecoveryCmd(*filename, cmdArgs[0], *generate, *words, out)
+
+	case "set-totp":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		setTOTPCmd(*filename, args[1])
+
+	case "open":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		noClipboard := fs.Bool("no-clipboard", false, "Only open the URL, without copying the password")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		openCmd(*filename, cmdArgs[0], *noClipboard, out)
+
+	case "set-url":
+		if len(args) < 3 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and URL required")
+			os.Exit(1)
+		}
+		setURLCmd(*filename, args[1], args[2])
+
+	case "login":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		loginCmd(*filename, args[1], out)
+
+	case "otpauth-uri":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		issuer := fs.String("issuer", "", "The issuer (default the name)")
+		copyURI := fs.Bool("copy", false, "Copy the URI to the clipboard instead of printing it")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		otpauthURICmd(*filename, cmdArgs[0], *issuer, *copyURI, out)
+
+	case "migrate":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		from := fs.String("from", "", "The passwords file to migrate")
+		fromBackend := fs.String("from-backend", "age", "The backend the file to migrate is encrypted with: scrypt or age")
+		identity := fs.String("identity", "", "An age identity file to decrypt with, instead of a passphrase")
+		parseArgs(fs, args[1:])
+		if *from == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "File to migrate from required")
+			os.Exit(1)
+		}
+		migrateCmd(*filename, *from, *fromBackend, *identity)
+
+	case "add-sshkey":
+		if len(args) < 3 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and key file required")
+			os.Exit(1)
+		}
+		addSSHKeyCmd(*filename, args[1], args[2])
+
+	case "get-sshkey":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		keyFile := fs.String("o", "", "The file to write the key to")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 || *keyFile == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and -o key file required")
+			os.Exit(1)
+		}
+		getSSHKeyCmd(*filename, cmdArgs[0], *keyFile)
+
+	case "weak":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		minBits := fs.Float64("min-bits", 60, "The minimum estimated entropy in bits")
+		parseArgs(fs, args[1:])
+		weakCmd(*filename, *minBits)
+
+	case "recent":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Index required, 1 for the most used password")
+			os.Exit(1)
+		}
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid index: %s\n", args[1])
+			os.Exit(1)
+		}
+		recentCmd(*filename, index, out)
+
+	case "by-username":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Username required")
+			os.Exit(1)
+		}
+		byUsernameCmd(*filename, args[1])
+
+	case "older-than":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Date required")
+			os.Exit(1)
+		}
+		olderThanCmd(*filename, args[1])
+
+	case "migrate-format":
+		migrateFormatCmd(*filename)
+
+	case "edit":
+		editCmd(*filename)
+
+	case "selftest":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		iterations := fs.Int("n", 10000, "The number of passwords to generate")
+		parseArgs(fs, args[1:])
+		selftestCmd(*passwordLength, charset, *iterations)
+
+	case "score":
+		scoreCmd(*filename)
+
+	case "export":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		redact := fs.Bool("redact", false, "Replace the password and other secrets with ****")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		exportCmd(*filename, cmdArgs[0], *redact)
+
+	case "export-jsonl":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		redact := fs.Bool("redact", false, "Replace passwords and other secrets with ****")
+		parseArgs(fs, args[1:])
+		exportJSONLCmd(*filename, *redact)
+
+	case "destroy":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		force := fs.Bool("force", false, "Allow destroying when stdin is not a terminal, the filename must still be given on stdin")
+		parseArgs(fs, args[1:])
+		destroyCmd(*filename, *force)
+
+	case "vault-note":
+		if len(args) < 2 {
+			vaultNoteCmd(*filename)
+		} else {
+			setVaultNoteCmd(*filename, args[1])
+		}
+
+	case "info":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		count := fs.Bool("count", false, "Also show the number of passwords, which requires decrypting the file")
+		parseArgs(fs, args[1:])
+		infoCmd(*filename, *count)
+
+	case "relocate":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		removeOld := fs.Bool("remove-old", false, "Remove the old passwords file afterward")
+		force := fs.Bool("force", false, "Replace the new file if it already exists")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "New path required")
+			os.Exit(1)
+		}
+		relocateCmd(*filename, cmdArgs[0], *removeOld, *force)
+
+	case "derive":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Site required")
+			os.Exit(1)
+		}
+		deriveCmd(*passwordLength, charset, out, args[1])
+
+	case "normalize-emails":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		apply := fs.Bool("apply", false, "Lowercase the domains, instead of just listing the entries")
+		parseArgs(fs, args[1:])
+		normalizeEmailsCmd(*filename, *apply)
+
+	case "inspect":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		inspectCmd(*filename, args[1])
+
+	case "audit-policy":
+		auditPolicyCmd(*filename)
+
+	case "push":
+		if len(args) < 3 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name and destination required")
+			os.Exit(1)
+		}
+		pushCmd(*filename, args[1], args[2])
+
+	case "undo":
+		undoCmd(*filename)
+
+	case "cleanup":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		keep := fs.Int("keep", 10, "The number of most recent backups to keep")
+		parseArgs(fs, args[1:])
+		cleanupCmd(*filename, *keep)
+
+	case "export-env":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		tag := fs.String("tag", "", "Only export passwords with this tag")
+		parseArgs(fs, args[1:])
+		exportEnvCmd(*filename, *tag)
+
+	case "browse":
+		browseCmd(*filename, out)
+
+	case "verify-backup":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Backup file required")
+			os.Exit(1)
+		}
+		verifyBackupCmd(*filename, args[1])
+
+	case "diff":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Other file required")
+			os.Exit(1)
+		}
+		diffCmd(*filename, args[1])
+
+	case "alias":
+		if len(args) < 4 {
+			_, _ = fmt.Fprintln(os.Stderr, "Subcommand (add or remove), name and alias required")
+			os.Exit(1)
+		}
+		aliasCmd(*filename, args[1], args[2], args[3])
+
+	case "tag":
+		if len(args) < 4 {
+			_, _ = fmt.Fprintln(os.Stderr, "Subcommand (add or remove), name and tag required")
+			os.Exit(1)
+		}
+		tagCmd(*filename, args[1], args[2], args[3])
+
+	case "tag-all":
+		if len(args) < 3 {
+			_, _ = fmt.Fprintln(os.Stderr, "Query and tag required")
+			os.Exit(1)
+		}
+		tagAllCmd(*filename, args[1], args[2])
+
+	case "tags":
+		tagsCmd(*filename)
+
+	case "verify-password":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+			os.Exit(1)
+		}
+		verifyPasswordCmd(*filename, args[1])
+
+	case "agent":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		ttl := fs.Duration("ttl", 15*time.Minute, "How long to cache the passphrase")
+		socket := fs.String("socket", "", "The unix socket to listen on (default a new socket in a private temporary directory)")
+		parseArgs(fs, args[1:])
+		agentCmd(*ttl, *socket)
+
+	case "lock":
+		lockCmd()
+
+	case "recover":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "New file required")
+			os.Exit(1)
+		}
+		recoverCmd(*filename, args[1])
+
+	case "import":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		report := fs.Bool("report", false, "Only print which passwords would conflict with existing ones and which are new, without importing")
+		onConflict := fs.String("on-conflict", "skip", "What to do with passwords which already exist: skip or overwrite")
+		cmdArgs := parseArgs(fs, args[1:])
+		if len(cmdArgs) < 1 {
+			_, _ = fmt.Fprintln(os.Stderr, "JSON or CSV file required")
+			os.Exit(1)
+		}
+		if *onConflict != "skip" && *onConflict != "overwrite" {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid -on-conflict: %s\n", *onConflict)
+			os.Exit(1)
+		}
+		importCmd(*filename, cmdArgs[0], *report, *onConflict == "overwrite")
+
+	case "import-keepass":
+		if len(args) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "KeePass CSV file required")
+			os.Exit(1)
+		}
+		importKeePassCmd(*filename, args[1])
+
+	case "clipboard-clear":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		after := fs.Duration("after", 0, "The time after which to clear the clipboard")
+		parseArgs(fs, args[1:])
+		clipboardClearCmd(*selection, *after)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		os.Exit(1)
+	}
+}
+
+// minScryptMaxMem is the smallest memory limit for scrypt which makes sense.
+const minScryptMaxMem = 1 << 20
+
+// setScryptLimits validates and sets the memory limit in bytes and time limit in seconds for scrypt, if not empty.
+func setScryptLimits(maxMem string, maxTime string) error {
+	if maxMem != "" {
+		n, err := strconv.ParseInt(maxMem, 10, 64)
+		if err != nil || n < minScryptMaxMem {
+			return fmt.Errorf("invalid GOPW_SCRYPT_MAXMEM: %s, must be a number of bytes of at least %d", maxMem, minScryptMaxMem)
+		}
+		pw.ScryptMaxMem = n
+	}
+	if maxTime != "" {
+		t, err := strconv.ParseFloat(maxTime, 64)
+		if err != nil || !(t > 0) || math.IsInf(t, 0) {
+			return fmt.Errorf("invalid GOPW_SCRYPT_MAXTIME: %s, must be a positive number of seconds", maxTime)
+		}
+		pw.ScryptMaxTime = t
+	}
+	return nil
+}
+
+// envOrDefault returns the value of the environment variable key, or defaultValue if it is not set.
+func envOrDefault(key string, defaultValue string) string {
+	if value, found := os.LookupEnv(key); found {
+		return value
+	}
+	return defaultValue
+}
+
+// isFlagSet reports whether the global option with the given name has been set on the command line.
+func isFlagSet(name string) bool {
+	return isFlagSetIn(flag.CommandLine, name)
+}
+
+// isFlagSetIn reports whether the option with the given name has been set in fs.
+func isFlagSetIn(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseArgs parses command arguments with fs, allowing options to be interspersed with positional arguments,
+// and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func initCmd(filename string) {
+	if err := pw.Init(filename); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s initialized\n", filename)
+}
+
+// getCmd outputs the password of an entry and prints the username, or outputs another field if field is
+// username, notes or recovery. If combined is set, the username and password are output together, separated by
+// separator. The entry is looked up by id if set, otherwise by name.
+func getCmd(filename string, name string, id string, accountName string, field string, noNewline bool, combined bool, separator string, out passwordOutput) {
+	var entry *pw.PasswordEntry
+	var err error
+	if id != "" {
+		entry, err = pw.GetByID(filename, id)
+	} else {
+		entry, err = pw.Get(filename, name)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputEntry(filename, entry, accountName, field, noNewline, combined, separator, out)
+}
+
+// outputEntry outputs the field of the account of the entry from the password file, for getCmd, and records the use
+// of the entry.
+func outputEntry(filename string, entry *pw.PasswordEntry, accountName string, field string, noNewline bool, combined bool, separator string, out passwordOutput) {
+	account, err := entry.Account(accountName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	switch {
+	case field == "username":
+		outputEntryField(account.Username, "username", entry.Name, out)
+	case field == "notes":
+		outputEntryField(entry.Notes, "notes", entry.Name, out)
+	case field == "recovery":
+		if entry.RecoveryPhrase == "" {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s has no recovery phrase\n", entry.Name)
+			os.Exit(1)
+		}
+		outputEntryField(entry.RecoveryPhrase, "recovery phrase", entry.Name, out)
+	case combined && account.Username != "":
+		outputEntryField(account.Username+separator+account.Password, "username and password", entry.Name, out)
+	default:
+		if account.Username != "" {
+			if noNewline {
+				fmt.Print(account.Username)
+			} else {
+				fmt.Println(account.Username)
+			}
+		}
+		outputEntryField(account.Password, "password", entry.Name, out)
+	}
+	if err := pw.RecordUse(filename, entry.Name); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: unable to record use: %v\n", err)
+	}
+}
+
+func listCmd(filename string, width int) {
+	entries, err := pw.List(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printEntries(entries, width)
+}
+
+// untaggedHeader is the header of the entries without tags in listByTagCmd.
+const untaggedHeader = "(untagged)"
+
+// listByTagCmd lists the entries grouped under a header for each tag, sorted by tag, with the untagged entries
+// last. Entries with multiple tags are listed under each of them.
+func listByTagCmd(filename string, width int) {
+	entries, err := pw.List(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	byTag := make(map[string][]pw.PasswordEntry)
+	var untagged []pw.PasswordEntry
+	for _, entry := range entries {
+		if len(entry.Tags) == 0 {
+			untagged = append(untagged, entry)
+		}
+		seen := make(map[string]bool, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				byTag[tag] = append(byTag[tag], entry)
+			}
+		}
+	}
+	tags := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	color := colorEnabled(os.Stdout)
+	group := func(header string, entries []pw.PasswordEntry) {
+		if color {
+			header = "\x1b[1;36m" + header + "\x1b[0m"
+		}
+		fmt.Println(header)
+		printEntriesIndented(entries, width, "  ")
+	}
+	for _, tag := range tags {
+		group(tag, byTag[tag])
+	}
+	if len(untagged) > 0 {
+		group(untaggedHeader, untagged)
+	}
+}
+
+// terminalWidth returns the width of the terminal if stdout is one, otherwise -1.
+func terminalWidth() int {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return -1
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// printEntries prints the names and usernames of the entries. If width is not negative, the usernames are
+// aligned, and if it is positive, lines are truncated to width characters with an ellipsis.
+func printEntries(entries []pw.PasswordEntry, width int) {
+	printEntriesIndented(entries, width, "")
+}
+
+// printEntriesIndented prints the entries like printEntries, with each line starting with indent.
+func printEntriesIndented(entries []pw.PasswordEntry, width int, indent string) {
+	if width < 0 {
+		for _, entry := range entries {
+			fmt.Printf("%s%s: %s\n", indent, entry.Name, entry.Username)
+		}
+		return
+	}
+	if width > 0 {
+		width = max(width-len(indent), 1)
+	}
+
+	nameWidth := 0
+	for _, entry := range entries {
+		nameWidth = max(nameWidth, len([]rune(entry.Name)))
+	}
+	// Leave at least half of the line for the username
+	if width > 0 && nameWidth > width/2 {
+		nameWidth = width / 2
+	}
+	for _, entry := range entries {
+		name := truncate(entry.Name, nameWidth)
+		line := strings.TrimRight(name+":"+strings.Repeat(" ", nameWidth-len([]rune(name))+1)+entry.Username, " ")
+		if width > 0 {
+			line = truncate(line, width)
+		}
+		fmt.Println(indent + line)
+	}
+}
+
+// truncate shortens s to at most width characters, ending with an ellipsis if shortened.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// parseDays parses a duration like time.ParseDuration, and also supports a number of days like 7d.
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// getDirCmd gets the entry with the given name from the password file in dir with it, like getCmd.
+func getDirCmd(dir string, name string, accountName string, field string, noNewline bool, combined bool, separator string, out passwordOutput) {
+	filename, entry, err := pw.FindShard(dir, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputEntry(filename, entry, accountName, field, noNewline, combined, separator, out)
+}
+
+func listDirCmd(dir string, width int) {
+	entries, err := pw.ListDir(dir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printEntries(entries, width)
+}
+
+func listSinceCmd(filename string, since string, width int) {
+	window, err := parseDays(since)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	entries, err := pw.ChangedSince(filename, time.Now().Add(-window))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printEntries(entries, width)
+}
+
+func listTemplateCmd(filename string, templateFile string, allowPasswords bool) {
+	tmpl, err := parseTemplateFile(templateFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: invalid template: %v\n", err)
+		os.Exit(1)
+	}
+	entries, err := pw.List(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := renderEntries(tmpl, entries, allowPasswords, os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// policyFlags are the options for a password generation policy to store with an entry.
+type policyFlags struct {
+	maxLength *int
+	minLower  *int
+	minUpper  *int
+	minDigit  *int
+	minSymbol *int
+}
+
+func addPolicyFlags(fs *flag.FlagSet) policyFlags {
+	return policyFlags{
+		maxLength: fs.Int("max-length", 0, "Maximum password length, stored in the generation policy"),
+		minLower:  fs.Int("min-lower", 0, "Minimum number of lowercase letters, stored in the generation policy"),
+		minUpper:  fs.Int("min-upper", 0, "Minimum number of uppercase letters, stored in the generation policy"),
+		minDigit:  fs.Int("min-digit", 0, "Minimum number of digits, stored in the generation policy"),
+		minSymbol: fs.Int("min-symbol", 0, "Minimum number of symbols, stored in the generation policy"),
+	}
+}
+
+// policy returns the generation policy given by the options, or nil if none of them are set.
+func (f policyFlags) policy(passwordLength int, passwordChars string) *pw.Policy {
+	if *f.maxLength == 0 && *f.minLower == 0 && *f.minUpper == 0 && *f.minDigit == 0 && *f.minSymbol == 0 {
+		return nil
+	}
+	return &pw.Policy{
+		MinLength: passwordLength,
+		MaxLength: *f.maxLength,
+		Charset:   passwordChars,
+		MinLower:  *f.minLower,
+		MinUpper:  *f.minUpper,
+		MinDigit:  *f.minDigit,
+		MinSymbol: *f.minSymbol,
+	}
+}
+
+// generate generates a password with the policy if not nil, otherwise with the length and charset.
+// If verbose is true, the strength of the password is printed to stderr.
+func generate(passwordLength int, passwordChars string, policy *pw.Policy, verbose bool) (string, error) {
+	var password string
+	var err error
+	if policy != nil {
+		passwordChars = policy.EffectiveCharset()
+		password, err = pw.GenerateWithPolicy(*policy)
+	} else {
+		password, err = pw.GeneratePassword(passwordLength, passwordChars)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if verbose {
+		length := len([]rune(password))
+		charsetSize := len([]rune(passwordChars))
+		_, _ = fmt.Fprintf(os.Stderr, "Generated %d-char password from %d-char charset (~%.0f bits entropy)\n",
+			length, charsetSize, pw.Strength(length, passwordChars))
+	}
+	return password, nil
+}
+
+// maxSiteProfileAttempts is the number of passwords to generate before giving up on complying with a site profile.
+const maxSiteProfileAttempts = 100
+
+// loadSiteProfile loads the site profile with the given name from GOPW_SITE_PROFILES, or site-profiles.json in the
+// gopw user config directory. It returns nil if name is empty.
+func loadSiteProfile(name string) *pw.SiteProfile {
+	if name == "" {
+		return nil
+	}
+	configFile := os.Getenv("GOPW_SITE_PROFILES")
+	if configFile == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		configFile = filepath.Join(configDir, "gopw", "site-profiles.json")
+	}
+	profile, err := pw.LoadSiteProfile(configFile, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return profile
+}
+
+// generateForSite generates a password like generate, and if a site profile is given, with the length and charset
+// fitted to the profile, regenerating it until it complies with the profile.
+func generateForSite(passwordLength int, passwordChars string, policy *pw.Policy, profile *pw.SiteProfile, verbose bool) (string, error) {
+	if profile == nil {
+		return generate(passwordLength, passwordChars, policy, verbose)
+	}
+	passwordLength, passwordChars = profile.Fit(passwordLength, passwordChars)
+	if passwordChars == "" {
+		return "", fmt.Errorf("no characters in the password charset are allowed by the site profile")
+	}
+	if policy != nil {
+		fitted := profile.FitPolicy(*policy)
+		if fitted.EffectiveCharset() == "" {
+			return "", fmt.Errorf("no characters in the policy charset are allowed by the site profile")
+		}
+		policy = &fitted
+	}
+	var violation error
+	for i := 0; i < maxSiteProfileAttempts; i++ {
+		password, err := generate(passwordLength, passwordChars, policy, verbose)
+		if err != nil {
+			return "", err
+		}
+		if violation = profile.Check(password); violation == nil {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("unable to generate a password complying with the site profile: %w", violation)
+}
+
+// addCmd adds an entry with a generated password, to the file in dir for the tag if dir is set.
+func addCmd(passwordLength int, passwordChars string, policy *pw.Policy, profile *pw.SiteProfile, verbose bool, hint bool, meter bool, out passwordOutput, filename string, dir string, tag string, name string, username string) {
+	password, err := generateForSite(passwordLength, passwordChars, policy, profile, verbose)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	entry := pw.PasswordEntry{
+		Name:      name,
+		Username:  username,
+		Password:  password,
+		GenPolicy: policy,
+	}
+	if tag != "" {
+		entry.Tags = []string{tag}
+	}
+	if hint {
+		entry.Notes = pw.PasswordHint(password)
+	}
+	if dir != "" {
+		err = pw.AddDir(dir, entry)
+	} else {
+		err = pw.Add(filename, entry)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if hint {
+		_, _ = fmt.Fprintf(os.Stderr, "Hint: %s\n", entry.Notes)
+	}
+	if meter {
+		if policy != nil {
+			passwordChars = policy.EffectiveCharset()
+		}
+		printMeter(pw.Strength(len([]rune(password)), passwordChars))
+	}
+	outputEntryField(password, "password", name, out)
+}
+
+func addTempCmd(passwordLength int, passwordChars string, ttl time.Duration, verbose bool, out passwordOutput, filename string, name string, username string) {
+	if ttl <= 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: TTL must be positive")
+		os.Exit(1)
+	}
+	password, err := generate(passwordLength, passwordChars, nil, verbose)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	expiresAt := time.Now().Add(ttl).UTC()
+	err = pw.Add(filename, pw.PasswordEntry{
+		Name:      name,
+		Username:  username,
+		Password:  password,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+func addAccountCmd(passwordLength int, passwordChars string, verbose bool, out passwordOutput, filename string, name string, username string) {
+	password, err := generate(passwordLength, passwordChars, nil, verbose)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	err = pw.AddAccount(filename, name, pw.Account{Username: username, Password: password})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+// errNotConfirmed is returned when the user does not confirm a change, to leave the entry unchanged.
+var errNotConfirmed = errors.New("not confirmed")
+
+// updateCmd generates a new password for an entry, with the stored generation policy unless a policy is given,
+// after showing the entry and asking for confirmation unless force is set. The confirmation is asked for while the
+// entry is modified, so that the file is only read once. The entry is looked up by id if set, otherwise by name.
+// Unless lengthSet is true, the password gets the same length as the current one.
+// If showOld is true, the old password is printed to stderr afterward, without a label if raw is true.
+func updateCmd(passwordLength int, lengthSet bool, passwordChars string, policy *pw.Policy, verbose bool, showOld bool, raw bool, force bool, out passwordOutput, filename string, name string, id string, username string) {
+	if !force && !term.IsTerminal(int(os.Stdin.Fd())) {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: stdin is not a terminal, use -force to update without confirmation")
+		os.Exit(1)
+	}
+	var password, oldPassword string
+	update := func(entry *pw.PasswordEntry) error {
+		if !force {
+			if err := confirmUpdate(entry, username); err != nil {
+				return err
+			}
+		}
+		name = entry.Name
+		if policy != nil {
+			entry.GenPolicy = policy
+		}
+		if !lengthSet && entry.Password != "" {
+			passwordLength = len([]rune(entry.Password))
+		}
+		var err error
+		password, err = generate(passwordLength, passwordChars, entry.GenPolicy, verbose)
+		if err != nil {
+			return err
+		}
+		oldPassword = entry.Password
+		entry.Username = username
+		entry.Password = password
+		return nil
+	}
+	var err error
+	if id != "" {
+		err = pw.ModifyByID(filename, id, update)
+	} else {
+		err = pw.Modify(filename, name, update)
+	}
+	if errors.Is(err, errNotConfirmed) {
+		_, _ = fmt.Fprintln(os.Stderr, "Password not updated")
+		return
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputEntryField(password, "password", name, out)
+	if showOld {
+		if raw {
+			_, _ = fmt.Fprintln(os.Stderr, oldPassword)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Old password: %s\n", oldPassword)
+		}
+	}
+}
+
+// confirmUpdate shows the entry and the username it gets, and asks whether to update it, returning errNotConfirmed
+// unless confirmed.
+func confirmUpdate(entry *pw.PasswordEntry, username string) error {
+	if entry.Username == username {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", entry.Name, entry.Username)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s, changing to %s\n", entry.Name, entry.Username, username)
+	}
+	answer, err := readLine(fmt.Sprintf("Rotate password for '%s'? [y/N] ", entry.Name))
+	if err != nil {
+		return err
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// getManyCmd prints the password entries with the names to stdout, tab separated or as JSON, and fails if any is
+// not found.
+func getManyCmd(filename string, names []string, jsonOutput bool) {
+	entries, missing, err := pw.GetMany(filename, names)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		if entries == nil {
+			entries = []pw.PasswordEntry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, entry := range entries {
+			fmt.Printf("%s\t%s\n", entry.Name, entry.Password)
+		}
+	}
+	for _, name := range missing {
+		_, _ = fmt.Fprintf(os.Stderr, "Not found: %s\n", name)
+	}
+	if len(missing) > 0 {
+		os.Exit(1)
+	}
+}
+
+func removeCmd(filename string, name string) {
+	if err := pw.Remove(filename, name); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func removeByIDCmd(filename string, id string) {
+	if err := pw.RemoveByID(filename, id); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func removeManyCmd(filename string, names []string, strict bool) {
+	var missing []string
+	var err error
+	if strict {
+		missing, err = pw.RemoveManyStrict(filename, names)
+	} else {
+		missing, err = pw.RemoveMany(filename, names)
+	}
+	for _, name := range missing {
+		_, _ = fmt.Fprintf(os.Stderr, "Not found: %s\n", name)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generateCmd(passwordLength int, passwordChars string, verbose bool, hint bool, meter bool, out passwordOutput) {
+	password, err := generate(passwordLength, passwordChars, nil, verbose)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if hint {
+		_, _ = fmt.Fprintf(os.Stderr, "Hint: %s\n", pw.PasswordHint(password))
+	}
+	if meter {
+		printMeter(pw.Strength(len([]rune(password)), passwordChars))
+	}
+	outputPassword(password, out)
+}
+
+// generateLayoutCmd generates a password from the easy typing charset layoutChars, and prints its entropy and how
+// much less it is than with charset.
+func generateLayoutCmd(passwordLength int, layoutChars string, charset string, hint bool, meter bool, out passwordOutput) {
+	password, err := pw.GeneratePassword(passwordLength, layoutChars)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	entropy := pw.Strength(passwordLength, layoutChars)
+	_, _ = fmt.Fprintf(os.Stderr, "%.1f bits of entropy, %.1f bits less than -password-charset at the same length\n",
+		entropy, pw.Strength(passwordLength, charset)-entropy)
+	if hint {
+		_, _ = fmt.Fprintf(os.Stderr, "Hint: %s\n", pw.PasswordHint(password))
+	}
+	if meter {
+		printMeter(pw.Strength(len([]rune(password)), layoutChars))
+	}
+	outputPassword(password, out)
+}
+
+// generateWeightedCmd generates a password with character classes weighted by weights, like lower=5,upper=2.
+func generateWeightedCmd(passwordLength int, weights string, out passwordOutput) {
+	classWeights := make(map[string]float64)
+	for _, weight := range strings.Split(weights, ",") {
+		class, value, found := strings.Cut(strings.TrimSpace(weight), "=")
+		parsed, err := strconv.ParseFloat(value, 64)
+		if !found || err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: invalid weight: %s, expected like lower=5\n", weight)
+			os.Exit(1)
+		}
+		classWeights[class] = parsed
+	}
+	password, err := pw.GenerateWeighted(passwordLength, classWeights)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+func bip39Cmd(words int, out passwordOutput) {
+	phrase, err := pw.GenerateBIP39(words)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(phrase, out)
+}
+
+// addRecoveryCmd sets the recovery phrase of an entry, either typed or generated, and outputs a generated one.
+func addRecoveryCmd(filename string, name string, generate bool, words int, out passwordOutput) {
+	var phrase string
+	var err error
+	if generate {
+		phrase, err = pw.GenerateBIP39(words)
+	} else {
+		phrase, err = readSecret("Recovery phrase: ")
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if strings.TrimSpace(phrase) == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: recovery phrase cannot be empty")
+		os.Exit(1)
+	}
+	if err := pw.SetRecoveryPhrase(filename, name, phrase); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if generate {
+		outputEntryField(phrase, "recovery phrase", name, out)
+	}
+}
+
+func generateBytesCmd(n int, encoding string, out passwordOutput) {
+	password, err := pw.GenerateBytes(n, encoding)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+func generateRequiringCmd(passwordLength int, passwordChars string, required string, out passwordOutput) {
+	password, err := pw.GenerateRequiring(passwordLength, passwordChars, required)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+func generateNoSequencesCmd(passwordLength int, passwordChars string, out passwordOutput) {
+	password, err := pw.GenerateNoSequences(passwordLength, passwordChars)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(password, out)
+}
+
+func diffCmd(filename string, other string) {
+	added, removed, changed, err := pw.Diff(filename, other)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range removed {
+		fmt.Printf("- %s\n", name)
+	}
+	for _, name := range added {
+		fmt.Printf("+ %s\n", name)
+	}
+	for _, name := range changed {
+		fmt.Printf("~ %s\n", name)
+	}
+}
+
+// verifyBackupCmd compares the backup with the passwords file, and lists the differences without the values.
+func verifyBackupCmd(filename string, backup string) {
+	file, err := os.Open(backup)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = file.Close() }()
+
+	added, removed, changed, err := pw.VerifyBackup(filename, file)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		fmt.Printf("%s matches %s\n", backup, filename)
+		return
+	}
+	for _, name := range removed {
+		fmt.Printf("- %s: not in backup\n", name)
+	}
+	for _, name := range added {
+		fmt.Printf("+ %s: only in backup\n", name)
+	}
+	for _, difference := range changed {
+		fmt.Printf("~ %s: %s differ\n", difference.Name, strings.Join(difference.Fields, ", "))
+	}
+	_ = file.Close()
+	os.Exit(1)
+}
+
+// passwordOutput describes where to output a password.
+type passwordOutput struct {
+	// fd is a file descriptor to write the password to instead of the clipboard, if not negative.
+	fd int
+	// file is the open file of fd, checked by openOutFd before anything is changed.
+	file *os.File
+	// selection is the X11 selection to copy the password to.
+	selection string
+	// clearAfter is the time after which to clear the clipboard, if positive.
+	clearAfter time.Duration
+	// quiet suppresses the confirmation of copying to the clipboard by outputEntryField.
+	quiet bool
+}
+
+// copy copies text to the clipboard, and schedules clearing it if clearAfter is set.
+func (out passwordOutput) copy(text string) error {
+	if err := copyToClipboard(text, out.selection); err != nil {
+		return fmt.Errorf("unable to access clipboard: %w", err)
+	}
+	if out.clearAfter > 0 {
+		if err := scheduleClipboardClear(text, out.selection, out.clearAfter); err != nil {
+			return fmt.Errorf("unable to schedule clearing of clipboard: %w", err)
+		}
+	}
+	return nil
+}
+
+// outputPassword copies the password to the clipboard, or writes it to a file descriptor.
+func outputPassword(password string, out passwordOutput) {
+	if out.fd < 0 {
+		if err := out.copy(password); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if _, err := fmt.Fprintln(out.file, password); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: file descriptor %d is not writable: %v\n", out.fd, err)
+		os.Exit(1)
+	}
+}
+
+// openOutFd returns the file of the file descriptor to write passwords to, checking that it is open for writing.
+func openOutFd(fd int) (*os.File, error) {
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not open: %w", fd, err)
+	}
+	// Writing nothing fails if the file descriptor is not open for writing
+	if _, err := f.Write(nil); err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not writable: %w", fd, err)
+	}
+	return f, nil
+}
+
+// outputEntryField outputs a field of an entry like outputPassword, and confirms copying it to the clipboard
+// on stderr with its length, but not its value.
+func outputEntryField(value string, field string, name string, out passwordOutput) {
+	outputPassword(value, out)
+	if out.fd < 0 && !out.quiet {
+		_, _ = fmt.Fprintf(os.Stderr, "Copied %s for '%s' (%d chars) to clipboard\n", field, name, len([]rune(value)))
+	}
+}
+
+func aliasCmd(filename string, subcommand string, name string, alias string) {
+	var err error
+	switch subcommand {
+	case "add":
+		err = pw.AddAlias(filename, name, alias)
+	case "remove":
+		err = pw.RemoveAlias(filename, name, alias)
+	default:
+		err = fmt.Errorf("unknown alias subcommand: %s", subcommand)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dicewareCmd(words int) {
+	phrase, entropy, err := pw.GenerateDiceware(words)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(phrase)
+	fmt.Printf("%.1f bits of entropy\n", entropy)
+}
+
+func memorableCmd(opts pw.MemorableOptions, out passwordOutput) {
+	password, err := pw.GenerateMemorable(opts)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%.1f bits of entropy\n", opts.Entropy())
+	outputPassword(password, out)
+}
+
+func recoverCmd(filename string, newFilename string) {
+	recovered, lost, err := pw.Recover(filename, newFilename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Recovered %d entries into %s, lost %d\n", recovered, newFilename, lost)
+}
+
+func importCmd(filename string, importFilename string, report bool, overwrite bool) {
+	importFile, err := os.Open(importFilename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var entries []pw.PasswordEntry
+	var skipped []pw.SkippedRow
+	if strings.EqualFold(filepath.Ext(importFilename), ".csv") {
+		entries, skipped, err = pw.ParseCSV(importFile)
+	} else {
+		entries, err = pw.ParseJSON(importFile)
+	}
+	_ = importFile.Close()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, row := range skipped {
+		_, _ = fmt.Fprintf(os.Stderr, "Skipped row %d: %s\n", row.Row, row.Reason)
+	}
+
+	if report {
+		conflicts, added, err := pw.ImportReport(filename, entries)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Conflicting:")
+		for _, name := range conflicts {
+			fmt.Printf("  %s\n", name)
+		}
+		fmt.Println("New:")
+		for _, name := range added {
+			fmt.Printf("  %s\n", name)
+		}
+		return
+	}
+
+	conflicts, err := pw.Import(filename, entries, overwrite)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if overwrite {
+		for _, name := range conflicts {
+			_, _ = fmt.Fprintf(os.Stderr, "Overwrote '%s'\n", name)
+		}
+		fmt.Printf("Imported %d entries, skipped %d\n", len(entries), len(skipped))
+	} else {
+		for _, name := range conflicts {
+			_, _ = fmt.Fprintf(os.Stderr, "Skipped '%s': already exists\n", name)
+		}
+		fmt.Printf("Imported %d entries, skipped %d\n", len(entries)-len(conflicts), len(skipped)+len(conflicts))
+	}
+}
+
+func importKeePassCmd(filename string, csvFilename string) {
+	csvFile, err := os.Open(csvFilename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	entries, skipped, err := pw.ParseKeePassCSV(csvFile)
+	_ = csvFile.Close()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, row := range skipped {
+		_, _ = fmt.Fprintf(os.Stderr, "Skipped row %d: %s\n", row.Row, row.Reason)
+	}
+
+	conflicts, err := pw.Import(filename, entries, false)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range conflicts {
+		_, _ = fmt.Fprintf(os.Stderr, "Skipped '%s': already exists\n", name)
+	}
+	fmt.Printf("Imported %d entries, skipped %d\n", len(entries)-len(conflicts), len(skipped)+len(conflicts))
+}
+
+func verifyPasswordCmd(filename string, name string) {
+	candidate, err := readSecret("Password: ")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to read password: %v\n", err)
+		os.Exit(1)
+	}
+	match, err := pw.VerifyPassword(filename, name, candidate)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !match {
+		fmt.Println("No match")
+		os.Exit(1)
+	}
+	fmt.Println("Match")
+}
+
+func agentCmd(ttl time.Duration, socket string) {
+	if socket == "" {
+		dir, err := os.MkdirTemp("", "gopw-agent-")
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = os.Remove(dir) }()
+		socket = filepath.Join(dir, "agent.sock")
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		_ = listener.Close()
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		_ = listener.Close()
+	}()
+
+	fmt.Printf("GOPW_AGENT_SOCK=%s; export GOPW_AGENT_SOCK;\n", socket)
+	// Close stdout, so that the agent can be started in the background with eval $(gopw agent &)
+	_ = os.Stdout.Close()
+	if err := pw.ServeAgent(listener, ttl); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func lockCmd() {
+	if err := pw.LockAgent(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func browseCmd(filename string, out passwordOutput) {
+	entries, err := pw.List(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := tea.NewProgram(newBrowseModel(entries, out), tea.WithAltScreen()).Run(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func setTOTPCmd(filename string, name string) {
+	secret, err := readSecret("TOTP secret: ")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to read TOTP secret: %v\n", err)
+		os.Exit(1)
+	}
+	var totp *pw.TOTP
+	if secret != "" {
+		totp = &pw.TOTP{Secret: secret}
+	}
+	if err := pw.SetTOTP(filename, name, totp); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func openCmd(filename string, name string, noClipboard bool, out passwordOutput) {
+	entry, err := pw.Get(filename, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if entry.URL == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s has no URL, set it with set-url\n", entry.Name)
+		os.Exit(1)
+	}
+	if !noClipboard {
+		outputPassword(entry.Password, out)
+	}
+	if err := openURL(entry.URL); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func setURLCmd(filename string, name string, url string) {
+	if err := pw.SetURL(filename, name, url); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loginCmd(filename string, name string, out passwordOutput) {
+	if out.fd >= 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: login copies to the clipboard, -out-fd is not supported")
+		os.Exit(1)
+	}
+	entry, err := pw.Get(filename, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if entry.TOTP == nil || entry.TOTP.Secret == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", pw.ErrNoTOTP)
+		os.Exit(1)
+	}
+
+	outputPassword(entry.Password, out)
+	_, _ = fmt.Fprint(os.Stderr, "Password copied, press Enter to copy the TOTP code")
+	if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr)
+	}
+
+	code, err := entry.TOTP.Code(time.Now())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(code, out)
+	_, _ = fmt.Fprintln(os.Stderr, "TOTP code copied")
+}
+
+func otpauthURICmd(filename string, name string, issuer string, copyURI bool, out passwordOutput) {
+	entry, err := pw.Get(filename, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	uri, err := pw.OTPAuthURI(*entry, issuer)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !copyURI {
+		fmt.Println(uri)
+		return
+	}
+	if err := out.copy(uri); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func migrateCmd(filename string, from string, fromBackendName string, identity string) {
+	fromBackend, err := pw.BackendByName(fromBackendName)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ageBackend, ok := fromBackend.(pw.AgeBackend); ok {
+		ageBackend.Identity = identity
+		fromBackend = ageBackend
+	}
+	if err := pw.Migrate(from, fromBackend, filename); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s migrated to %s\n", from, filename)
+}
+
+func addSSHKeyCmd(filename string, name string, keyFile string) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := pw.AddSSHKey(filename, name, key); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func getSSHKeyCmd(filename string, name string, keyFile string) {
+	key, err := pw.GetSSHKey(filename, name)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = f.Close() }()
+	// The permissions of an existing file are not changed by OpenFile
+	if err := f.Chmod(0600); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := f.Write(key); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// weakCmd prints the names and estimated strengths of weak passwords, sorted by name.
+func weakCmd(filename string, minBits float64) {
+	weak, err := pw.WeakPasswordStrengths(filename, minBits)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, password := range weak {
+		fmt.Printf("%s: %.0f bits\n", password.Name, password.Entropy)
+	}
+}
+
+// recentCmd gets the password which is the index:th most used, counting from 1.
+func recentCmd(filename string, index int, out passwordOutput) {
+	names, err := pw.MostUsed(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(names) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: no used passwords recorded yet, see -recent-file")
+		os.Exit(1)
+	}
+	if index < 1 || index > len(names) {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: index must be from 1 to %d, the number of used passwords\n", len(names))
+		os.Exit(1)
+	}
+	getCmd(filename, names[index-1], "", "", "password", false, false, "", out)
+}
+
+// dateLayouts are the supported layouts of dates on the command line.
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04:05", time.RFC3339}
+
+// parseDate parses a date like 2023-01-01, optionally with a time, in local time unless a time zone is given.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), time.Local); err == nil {
+			return t, nil
 		}
-		getCmd(*filename, args[1])
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s, expected YYYY-MM-DD", s)
+}
 
-	case "list":
-		listCmd(*filename)
+func byUsernameCmd(filename string, username string) {
+	names, err := pw.ByUsername(filename, username)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(names) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "No passwords with username %s\n", username)
+		return
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
 
-	case "add":
-		if len(args) < 3 {
-			_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
-			os.Exit(1)
+func olderThanCmd(filename string, date string) {
+	before, err := parseDate(date)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	older, unknown, err := pw.OlderThan(filename, before)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, entry := range older {
+		fmt.Printf("%s: %s\n", entry.Name, entry.UpdatedAt.Local().Format("2006-01-02"))
+	}
+	for _, entry := range unknown {
+		fmt.Printf("%s: unknown age\n", entry.Name)
+	}
+}
+
+func migrateFormatCmd(filename string) {
+	from, to, err := pw.MigrateFormat(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if from == to {
+		fmt.Printf("%s is already in format version %d, nothing to migrate\n", filename, from)
+	} else {
+		fmt.Printf("Migrated %s from format version %d to %d\n", filename, from, to)
+	}
+}
+
+// editCmd lets the user edit all entries as JSON with $VISUAL or $EDITOR in a temporary file, which is only removed
+// if the edited entries are written successfully, so that the changes are not lost otherwise.
+func editCmd(filename string) {
+	content, err := pw.EditJSON(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	tmpFile, err := os.CreateTemp("", "gopw-edit-*.json")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to create temporary file: %v\n", err)
+		os.Exit(1)
+	}
+	tmpFilename := tmpFile.Name()
+	err = tmpFile.Chmod(0600)
+	if err == nil {
+		_, err = tmpFile.Write(content)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpFilename)
+		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to write temporary file: %v\n", err)
+		os.Exit(1)
+	}
+
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], tmpFilename)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: editor %s failed: %v\nThe edited file is kept in %s\n", editor[0], err, tmpFilename)
+		os.Exit(1)
+	}
+
+	edited, err := os.ReadFile(tmpFilename)
+	if err == nil {
+		if bytes.Equal(edited, content) {
+			_ = os.Remove(tmpFilename)
+			fmt.Println("No changes")
+			return
 		}
-		addCmd(*passwordLength, *passwordChars, *filename, args[1], args[2])
+		err = pw.ReplaceJSON(filename, edited)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\nThe edited file is kept in %s\n", err, tmpFilename)
+		os.Exit(1)
+	}
+	_ = os.Remove(tmpFilename)
+}
 
-	case "update":
-		if len(args) < 3 {
-			_, _ = fmt.Fprintln(os.Stderr, "Name and username required")
-			os.Exit(1)
+// selftestCmd checks that random bytes can be read, and measures the throughput of generating passwords.
+func selftestCmd(passwordLength int, passwordChars string, iterations int) {
+	if iterations <= 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: number of passwords must be positive")
+		os.Exit(1)
+	}
+
+	failed := false
+	if _, err := pw.GenerateBytes(32, "hex"); err != nil {
+		fmt.Printf("crypto/rand: %v\n", err)
+		failed = true
+	} else {
+		fmt.Println("crypto/rand: ok")
+	}
+
+	errorCount := 0
+	var firstErr error
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		if _, err := pw.GeneratePassword(passwordLength, passwordChars); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			errorCount++
 		}
-		updateCmd(*passwordLength, *passwordChars, *filename, args[1], args[2])
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Generated %d passwords of length %d in %v, %.0f passwords/sec\n",
+		iterations, passwordLength, elapsed.Round(time.Millisecond), float64(iterations)/elapsed.Seconds())
+	if errorCount > 0 {
+		fmt.Printf("Errors: %d, first: %v\n", errorCount, firstErr)
+		failed = true
+	}
 
-	case "remove":
-		if len(args) < 2 {
-			_, _ = fmt.Fprintln(os.Stderr, "Name required")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func scoreCmd(filename string) {
+	result, err := pw.Score(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Score: %d/100\n", result.Score)
+	for _, deduction := range result.Deductions {
+		fmt.Printf("-%d %s: %s\n", deduction.Points, deduction.Reason, strings.Join(deduction.Names, ", "))
+	}
+}
+
+func destroyCmd(filename string, force bool) {
+	if !force && !term.IsTerminal(int(os.Stdin.Fd())) {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: stdin is not a terminal, use -force to destroy anyway")
+		os.Exit(1)
+	}
+	confirmation, err := readLine(fmt.Sprintf("This destroys %s and its backups, mirror and shares permanently. Type the filename to confirm: ", filename))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if confirmation != filename {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: filename does not match, nothing destroyed")
+		os.Exit(1)
+	}
+	if err := pw.Destroy(filename); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s destroyed\n", filename)
+	_, _ = fmt.Fprintln(os.Stderr, "Note: on SSDs and journaling or copy-on-write file systems, old content may still be recoverable")
+}
+
+func vaultNoteCmd(filename string) {
+	description, err := pw.Description(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if description != "" {
+		fmt.Println(description)
+	}
+}
+
+func setVaultNoteCmd(filename string, description string) {
+	if err := pw.SetDescription(filename, description); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func infoCmd(filename string, count bool) {
+	info, err := pw.Info(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("path: %s\n", info.Path)
+	fmt.Printf("size: %d bytes\n", info.Size)
+	fmt.Printf("modified: %s\n", info.ModTime.Format(time.RFC3339))
+	fmt.Printf("backend: %s\n", info.Backend)
+	if info.Sealed {
+		fmt.Println("sealed: yes")
+	}
+	if count {
+		n, err := pw.Count(filename)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: unable to count passwords: %v\n", err)
 			os.Exit(1)
 		}
-		removeCmd(*filename, args[1])
+		fmt.Printf("passwords: %d\n", n)
+	}
+}
 
-	case "generate":
-		generateCmd(*passwordLength, *passwordChars)
+func relocateCmd(filename string, newFilename string, removeOld bool, force bool) {
+	if _, err := os.Stat(newFilename); err == nil && !force {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s already exists, use -force to replace it\n", newFilename)
+		os.Exit(1)
+	}
+	if err := pw.Relocate(filename, newFilename, removeOld); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s relocated to %s\n", filename, newFilename)
+}
 
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
+func deriveCmd(passwordLength int, passwordChars string, out passwordOutput, site string) {
+	master, err := readSecret("Master secret: ")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	password, err := pw.DerivePassword(master, site, passwordLength, passwordChars)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	outputPassword(password, out)
 }
 
-func initCmd(filename string) {
-	if err := pw.Init(filename); err != nil {
+func normalizeEmailsCmd(filename string, apply bool) {
+	names, err := pw.NormalizeEmails(filename, apply)
+	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("%s initialized\n", filename)
+	for _, name := range names {
+		fmt.Println(name)
+	}
 }
 
-func getCmd(filename string, name string) {
-	entry, err := pw.Get(filename, name)
+func inspectCmd(filename string, name string) {
+	info, err := pw.Inspect(filename, name)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if entry.Username != "" {
-		fmt.Println(entry.Username)
+	fmt.Printf("contains: %s\n", strings.Join(info.Classes, ", "))
+	fmt.Printf("length: %d\n", info.Length)
+	fmt.Printf("entropy: %.0f bits\n", info.Entropy)
+}
+
+func exportCmd(filename string, name string, redact bool) {
+	if !redact {
+		_, _ = fmt.Fprintln(os.Stderr, "Warning: exporting password in plaintext")
 	}
-	if err = clipboard.WriteAll(entry.Password); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to access clipboard: %v\n", err)
+	if err := pw.ExportEntry(filename, name, redact, os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func listCmd(filename string) {
-	entries, err := pw.List(filename)
+func exportJSONLCmd(filename string, redact bool) {
+	var err error
+	if redact {
+		err = pw.ExportRedacted(filename, os.Stdout)
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, "Warning: exporting passwords in plaintext")
+		err = pw.ExportJSONL(filename, os.Stdout)
+	}
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	for _, entry := range entries {
-		fmt.Printf("%s: %s\n", entry.Name, entry.Username)
+}
+
+func auditPolicyCmd(filename string) {
+	violations, err := pw.AuditPolicy(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	names := make([]string, 0, len(violations))
+	for name := range violations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, violations[name])
 	}
 }
 
-func addCmd(passwordLength int, passwordChars string, filename string, name string, username string) {
-	password, err := pw.GeneratePassword(passwordLength, passwordChars)
+func pushCmd(filename string, name string, destination string) {
+	host, path, err := parsePushDestination(destination)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	err = pw.Add(filename, pw.PasswordEntry{
-		Name:     name,
-		Username: username,
-		Password: password,
-	})
+	entry, err := pw.Get(filename, name)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if err = clipboard.WriteAll(password); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to access clipboard: %v\n", err)
+	if err := pushPassword(entry.Password, host, path); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func updateCmd(passwordLength int, passwordChars string, filename string, name string, username string) {
-	password, err := pw.GeneratePassword(passwordLength, passwordChars)
+func cleanupCmd(filename string, keep int) {
+	sizeBefore, err := pw.BackupsSize(filename)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	err = pw.Update(filename, pw.PasswordEntry{
-		Name:     name,
-		Username: username,
-		Password: password,
-	})
+	removed, err := pw.PruneBackups(filename, keep)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if err = clipboard.WriteAll(password); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to access clipboard: %v\n", err)
+	sizeAfter, err := pw.BackupsSize(filename)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Removed %d backups, reclaimed %d bytes\n", removed, sizeBefore-sizeAfter)
 }
 
-func removeCmd(filename string, name string) {
-	if err := pw.Remove(filename, name); err != nil {
+func undoCmd(filename string) {
+	if err := pw.Undo(filename); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func luhnCmd(length int, out passwordOutput) {
+	number, err := pw.GenerateLuhn(length)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPassword(number, out)
+}
+
+func tagCmd(filename string, subcommand string, name string, tag string) {
+	var err error
+	switch subcommand {
+	case "add":
+		err = pw.AddTag(filename, name, tag)
+	case "remove":
+		err = pw.RemoveTag(filename, name, tag)
+	default:
+		err = fmt.Errorf("unknown tag subcommand: %s", subcommand)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func tagAllCmd(filename string, query string, tag string) {
+	query = strings.ToLower(query)
+	tagged, err := pw.TagWhere(filename, func(entry pw.PasswordEntry) bool {
+		return strings.Contains(strings.ToLower(entry.Name), query) ||
+			strings.Contains(strings.ToLower(entry.Username), query)
+	}, tag)
+	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Tagged %d passwords\n", tagged)
 }
 
-func generateCmd(passwordLength int, passwordChars string) {
-	password, err := pw.GeneratePassword(passwordLength, passwordChars)
+func tagsCmd(filename string) {
+	counts, err := pw.TagCounts(filename)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if err = clipboard.WriteAll(password); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: unable to access clipboard: %v\n", err)
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	for _, tag := range tags {
+		fmt.Printf("%s: %d\n", tag, counts[tag])
+	}
+}
+
+func exportEnvCmd(filename string, tag string) {
+	var filter func(pw.PasswordEntry) bool
+	if tag != "" {
+		filter = func(entry pw.PasswordEntry) bool {
+			return entry.HasTag(tag)
+		}
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "Warning: exporting passwords in plaintext")
+	if err := pw.ExportEnv(filename, filter, os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	// meterWidth is the number of cells of the strength meter bar.
+	meterWidth = 10
+	// meterFullBits is the entropy in bits filling the bar.
+	meterFullBits = 128
+	// meterWeakBits and meterFairBits are the entropy in bits below which the bar is red and yellow.
+	meterWeakBits = 60
+	meterFairBits = 90
+)
+
+// printMeter prints a strength meter bar like "[#######---] 72 bits" for entropy in bits to stderr, colored red,
+// yellow or green on a terminal unless NO_COLOR is set.
+func printMeter(bits float64) {
+	filled := min(int(math.Round(bits/meterFullBits*meterWidth)), meterWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", meterWidth-filled)
+	if colorEnabled(os.Stderr) {
+		color := "32"
+		switch {
+		case bits < meterWeakBits:
+			color = "31"
+		case bits < meterFairBits:
+			color = "33"
+		}
+		bar = "\x1b[" + color + "m" + bar + "\x1b[0m"
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "[%s] %.0f bits\n", bar, bits)
+}
+
+// colorEnabled reports whether to use ANSI colors for output to file, which is when it is a terminal and NO_COLOR
+// is not set.
+func colorEnabled(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd())) && os.Getenv("NO_COLOR") == ""
+}
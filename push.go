@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// pushDestination matches a destination for push, like user@host:/path.
+var pushDestination = regexp.MustCompile(`^([^@\s:]+@[^@\s:]+):(\S+)$`)
+
+// parsePushDestination splits a destination like user@host:/path into user@host and /path.
+func parsePushDestination(destination string) (string, string, error) {
+	match := pushDestination.FindStringSubmatch(destination)
+	if match == nil {
+		return "", "", fmt.Errorf("invalid destination %s, must be like user@host:/path", destination)
+	}
+	return match[1], match[2], nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// pushPassword writes the password to the file path on the remote host with the ssh command,
+// creating it readable only by the owner. The host is given after -- so that it cannot be taken as an option.
+func pushPassword(password string, host string, path string) error {
+	cmd := exec.Command("ssh", "--", host, "umask 077 && cat > "+shellQuote(path))
+	cmd.Stdin = strings.NewReader(password)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to execute ssh: %w", err)
+	}
+	return nil
+}
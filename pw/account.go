@@ -0,0 +1,68 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrAccountAlreadyExists = errors.New("account already exists")
+	ErrAccountRequired      = errors.New("entry has multiple accounts, account must be specified")
+	ErrAmbiguousAccount     = errors.New("multiple accounts with the same username")
+)
+
+// Account is an additional username and password under a password entry.
+type Account struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// accounts returns all accounts of the entry, starting with its own username and password.
+func (e PasswordEntry) accounts() []Account {
+	return append([]Account{{Username: e.Username, Password: e.Password}}, e.Accounts...)
+}
+
+// Account returns the account of the entry with the given username. If username is empty, the entry must have
+// only one account, which is returned.
+func (e PasswordEntry) Account(username string) (Account, error) {
+	accounts := e.accounts()
+	if username == "" {
+		if len(accounts) > 1 {
+			return Account{}, ErrAccountRequired
+		}
+		return accounts[0], nil
+	}
+
+	var found []Account
+	for _, account := range accounts {
+		if account.Username == username {
+			found = append(found, account)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Account{}, ErrAccountNotFound
+	case 1:
+		return found[0], nil
+	default:
+		return Account{}, ErrAmbiguousAccount
+	}
+}
+
+// AddAccount adds an additional account to the password entry with the given name.
+func AddAccount(filename string, name string, account Account) error {
+	if len(account.Username) == 0 {
+		return fmt.Errorf("username cannot be empty")
+	}
+
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		for _, existing := range entry.accounts() {
+			if existing.Username == account.Username {
+				return ErrAccountAlreadyExists
+			}
+		}
+		entry.Accounts = append(entry.Accounts, account)
+		return nil
+	})
+}
@@ -0,0 +1,231 @@
+package pw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// AgentSocket is the path to the unix socket of a running agent, see ServeAgent.
+// If set, files are decrypted and encrypted by the agent, falling back to prompting for the passphrase
+// with scrypt if the agent cannot be reached.
+var AgentSocket string
+
+// AgentPassphrase is called to prompt for the passphrase with the prompt when the agent has no passphrase cached.
+var AgentPassphrase func(prompt string) (string, error)
+
+var (
+	ErrAgentLocked      = errors.New("agent is locked")
+	errAgentUnavailable = errors.New("agent unavailable")
+)
+
+const (
+	agentOpDecrypt = "decrypt"
+	agentOpEncrypt = "encrypt"
+	agentOpUnlock  = "unlock"
+	agentOpLock    = "lock"
+)
+
+type agentRequest struct {
+	Op         string `json:"op"`
+	Data       []byte `json:"data,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type agentResponse struct {
+	Data   []byte `json:"data,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// agent caches the passphrase, similar to ssh-agent.
+type agent struct {
+	ttl        time.Duration
+	mu         sync.Mutex
+	passphrase string
+	timer      *time.Timer
+}
+
+// ServeAgent serves decrypt and encrypt requests from clients on the listener, until the listener is closed.
+// The agent starts locked, and caches the passphrase for ttl after a client unlocks it.
+func ServeAgent(listener net.Listener, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+
+	a := &agent{ttl: ttl}
+	defer a.lock()
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go a.serve(conn)
+	}
+}
+
+func (a *agent) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	var req agentRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	var resp agentResponse
+	switch req.Op {
+	case agentOpUnlock:
+		a.unlock(req.Passphrase)
+
+	case agentOpLock:
+		a.lock()
+
+	case agentOpDecrypt, agentOpEncrypt:
+		passphrase := a.cachedPassphrase()
+		if passphrase == "" {
+			resp.Locked = true
+			break
+		}
+		op := "dec"
+		if req.Op == agentOpEncrypt {
+			op = "enc"
+		}
+		output, err := scryptWithPassphrase(op, req.Data, passphrase)
+		if err != nil {
+			// Most likely a wrong passphrase, so forget it
+			a.lock()
+			resp.Error = err.Error()
+		}
+		resp.Data = output
+
+	default:
+		resp.Error = fmt.Sprintf("unknown agent operation: %s", req.Op)
+	}
+
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+func (a *agent) unlock(passphrase string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.passphrase = passphrase
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.timer = time.AfterFunc(a.ttl, a.lock)
+}
+
+func (a *agent) lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.passphrase = ""
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+}
+
+func (a *agent) cachedPassphrase() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.passphrase
+}
+
+// LockAgent makes the agent at AgentSocket forget the cached passphrase.
+func LockAgent() error {
+	if AgentSocket == "" {
+		return fmt.Errorf("no agent socket specified")
+	}
+
+	_, err := callAgent(agentRequest{Op: agentOpLock})
+	return err
+}
+
+func agentDecrypt(filename string) ([]byte, error) {
+	ciphertext, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return callAgentUnlocking(agentRequest{Op: agentOpDecrypt, Data: ciphertext})
+}
+
+func agentEncrypt(filename string, data []byte) error {
+	ciphertext, err := callAgentUnlocking(agentRequest{Op: agentOpEncrypt, Data: data})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, ciphertext, 0600)
+}
+
+// callAgentUnlocking calls the agent, prompting for the passphrase to unlock it if needed. The passphrase is
+// prompted for twice when encrypting, like scrypt enc does, since a mistyped passphrase would not be detected.
+func callAgentUnlocking(req agentRequest) ([]byte, error) {
+	resp, err := callAgent(req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Locked {
+		return resp.Data, nil
+	}
+
+	if AgentPassphrase == nil {
+		return nil, ErrAgentLocked
+	}
+	passphrase, err := AgentPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read passphrase: %w", err)
+	}
+	if req.Op == agentOpEncrypt {
+		confirmation, err := AgentPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, fmt.Errorf("unable to read passphrase: %w", err)
+		}
+		if passphrase != confirmation {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	if _, err := callAgent(agentRequest{Op: agentOpUnlock, Passphrase: passphrase}); err != nil {
+		return nil, err
+	}
+
+	resp, err = callAgent(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Locked {
+		return nil, ErrAgentLocked
+	}
+	return resp.Data, nil
+}
+
+func callAgent(req agentRequest) (*agentResponse, error) {
+	conn, err := net.Dial("unix", AgentSocket)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAgentUnavailable, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("unable to send request to agent: %w", err)
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("unable to receive response from agent: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,98 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Armor makes password files be written armored, with the encrypted file base64 encoded in lines between a header
+// and a footer, so that it is a text file, e.g. for committing to git. Armored password files are detected when
+// read. A password file which is armored is always written armored again.
+var Armor bool
+
+const (
+	armorHeader = "-----BEGIN GOPW ENCRYPTED FILE-----"
+	armorFooter = "-----END GOPW ENCRYPTED FILE-----"
+	// armorLineLength is the length of the base64 lines of an armored file.
+	armorLineLength = 64
+)
+
+// isArmored reports whether the content of a password file is armored.
+func isArmored(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte(armorHeader))
+}
+
+// fileIsArmored reports whether the password file exists and is armored, also if it is split.
+func fileIsArmored(filename string) bool {
+	content, err := readStored(filename)
+	return err == nil && isArmored(content)
+}
+
+// armor returns the encrypted data armored.
+func armor(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	buf.WriteString(armorHeader + "\n")
+	for len(encoded) > armorLineLength {
+		buf.WriteString(encoded[:armorLineLength] + "\n")
+		encoded = encoded[armorLineLength:]
+	}
+	if encoded != "" {
+		buf.WriteString(encoded + "\n")
+	}
+	buf.WriteString(armorFooter + "\n")
+	return buf.Bytes()
+}
+
+// dearmor returns the encrypted data in armored content.
+func dearmor(content []byte) ([]byte, error) {
+	body, found := bytes.CutPrefix(bytes.TrimSpace(content), []byte(armorHeader))
+	if found {
+		body, found = bytes.CutSuffix(body, []byte(armorFooter))
+	}
+	if !found {
+		return nil, fmt.Errorf("invalid armored file: missing header or footer")
+	}
+	data, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(body), nil)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid armored file: %w", err)
+	}
+	return data, nil
+}
+
+// dearmorFile decodes the armored content of the password file into a temporary file, and returns its name.
+// The caller must remove the temporary file.
+func dearmorFile(filename string, content []byte) (string, error) {
+	data, err := dearmor(content)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("unable to write temporary file: %w", err)
+	}
+	return tmpFile.Name(), nil
+}
+
+// armorFile replaces the encrypted content of the file with the armored content.
+func armorFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, armor(data), FileMode); err != nil {
+		return fmt.Errorf("unable to write armored file: %w", err)
+	}
+	return nil
+}
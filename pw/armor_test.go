@@ -0,0 +1,80 @@
+package pw
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestArmorDearmor(t *testing.T) {
+	for _, length := range []int{0, 1, 47, 48, 49, 200} {
+		data := make([]byte, length)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		armored := armor(data)
+		if !isArmored(armored) {
+			t.Errorf("armored %d bytes is not detected as armored: %s", length, armored)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(armored), "\n"), "\n") {
+			if len(line) > armorLineLength && line != armorHeader && line != armorFooter {
+				t.Errorf("line longer than %d: %s", armorLineLength, line)
+			}
+		}
+		// Line endings and surrounding whitespace may be changed, e.g. by git
+		dearmored, err := dearmor(bytes.ReplaceAll(append([]byte("\n"), armored...), []byte("\n"), []byte("\r\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dearmored, data) {
+			t.Errorf("dearmored %x, expected %x", dearmored, data)
+		}
+	}
+
+	for _, content := range []string{
+		armorHeader + "\nAAAA\n",
+		"AAAA\n" + armorFooter + "\n",
+		armorHeader + "\nnot base64!\n" + armorFooter + "\n",
+	} {
+		if _, err := dearmor([]byte(content)); err == nil {
+			t.Errorf("expected an error dearmoring %q", content)
+		}
+	}
+}
+
+func TestArmoredRoundTrip(t *testing.T) {
+	filename := newTestFile(t)
+	Armor = true
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	Armor = false
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stays armored when written without Armor
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isArmored(content) {
+		t.Fatalf("not armored: %s", content)
+	}
+	data, err := dearmor(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, testMagic) {
+		t.Errorf("armored content is not the encrypted file: %q", data)
+	}
+
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret" {
+		t.Errorf("got %+v", entry)
+	}
+}
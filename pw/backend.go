@@ -0,0 +1,225 @@
+package pw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+)
+
+// Backend encrypts and decrypts password files.
+type Backend interface {
+	// Name returns the name of the backend.
+	Name() string
+	// Decrypt returns the decrypted contents of the file.
+	Decrypt(filename string) ([]byte, error)
+	// Encrypt encrypts data to the file.
+	Encrypt(filename string, data []byte) error
+}
+
+// Context is the context of running scrypt and other external commands, which are killed when it is done.
+var Context = context.Background()
+
+// DefaultBackend is the backend used for the password file.
+var DefaultBackend Backend = ScryptBackend{}
+
+// BackendByName returns the backend with the given name, scrypt or age.
+func BackendByName(name string) (Backend, error) {
+	switch name {
+	case "scrypt":
+		return ScryptBackend{}, nil
+	case "age":
+		return AgeBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend: %s", name)
+	}
+}
+
+// Migrate decrypts the file from with the backend fromBackend, and writes its entries to the new password file to.
+// The file from is left untouched.
+func Migrate(from string, fromBackend Backend, to string) error {
+	if len(from) == 0 || len(to) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	if _, err := os.Stat(to); err == nil {
+		return ErrPwFileAlreadyExists
+	}
+
+	output, err := decryptWith(fromBackend, from)
+	if err != nil {
+		return err
+	}
+
+	doc, err := unmarshalDocument(output)
+	if err != nil {
+		return fmt.Errorf("invalid JSON in %s: %w", from, err)
+	}
+	if err := registerSealKey(from, doc); err != nil {
+		return err
+	}
+	data := doc.Entries
+	for i, entry := range data {
+		if entry.Name == "" {
+			return fmt.Errorf("entry %d in %s has no name", i+1, from)
+		}
+		if err := unsealWith(fromBackend, &data[i]); err != nil {
+			return err
+		}
+	}
+
+	// The description and the entries are unsealed, so that the new file gets its own key if sealed
+	description, err := doc.descriptionWith(fromBackend)
+	if err != nil {
+		return err
+	}
+	return write(to, &document{Description: description, Entries: data})
+}
+
+// Passphrase provides the passphrase for ScryptBackend, if set. Otherwise scrypt prompts for it.
+var Passphrase func() (string, error)
+
+// ScryptBackend encrypts with the scrypt command line utility, or with the agent if AgentSocket is set. Built with
+// the nativecrypt build tag, it encrypts the same file format natively instead, without the scrypt utility.
+type ScryptBackend struct{}
+
+func (ScryptBackend) Name() string {
+	return "scrypt"
+}
+
+func (ScryptBackend) Decrypt(filename string) ([]byte, error) {
+	if Passphrase != nil {
+		passphrase, err := Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return scryptWithPassphrase("dec", ciphertext, passphrase)
+	}
+
+	if AgentSocket != "" {
+		output, err := agentDecrypt(filename)
+		if !errors.Is(err, errAgentUnavailable) {
+			return output, err
+		}
+		slog.Warn("falling back to prompting", "error", err)
+	}
+
+	return scryptDecrypt(filename)
+}
+
+func (ScryptBackend) Encrypt(filename string, data []byte) error {
+	if Passphrase != nil {
+		passphrase, err := Passphrase()
+		if err != nil {
+			return err
+		}
+		ciphertext, err := scryptWithPassphrase("enc", data, passphrase)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filename, ciphertext, 0600)
+	}
+
+	if AgentSocket != "" {
+		err := agentEncrypt(filename, data)
+		if !errors.Is(err, errAgentUnavailable) {
+			return err
+		}
+		slog.Warn("falling back to prompting", "error", err)
+	}
+
+	return scryptEncrypt(filename, data)
+}
+
+// ScryptMaxMem is the maximum memory in bytes for scrypt to use, passed as its -M option, if positive.
+// The native scrypt implementation also uses it as a limit.
+var ScryptMaxMem int64
+
+// ScryptMaxTime is the maximum time in seconds for scrypt to use, passed as its -t option, if positive.
+// The native scrypt implementation does not use it.
+var ScryptMaxTime float64
+
+// DecryptRetries is the number of times to run scrypt again, prompting for the passphrase, if decrypting fails
+// because of a wrong passphrase.
+var DecryptRetries int
+
+// ErrWrongPassphrase is returned if scrypt fails to decrypt since the passphrase is incorrect.
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// scryptDecrypt decrypts the file with scrypt, which prompts for the passphrase, retrying up to DecryptRetries
+// times if the passphrase is wrong.
+func scryptDecrypt(filename string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		output, err := scryptDecryptOnce(filename)
+		if errors.Is(err, ErrWrongPassphrase) && attempt < DecryptRetries {
+			_, _ = fmt.Fprintln(os.Stderr, "Wrong passphrase, try again")
+			continue
+		}
+		return output, err
+	}
+}
+
+// AgeBackend encrypts with the age command line utility, see https://age-encryption.org/.
+// It uses a passphrase, unless Identity is set.
+type AgeBackend struct {
+	// Identity is an age identity file to decrypt with, if set.
+	Identity string
+}
+
+func (AgeBackend) Name() string {
+	return "age"
+}
+
+func (b AgeBackend) Decrypt(filename string) ([]byte, error) {
+	args := []string{"--decrypt"}
+	if b.Identity != "" {
+		args = append(args, "--identity", b.Identity)
+	}
+	args = append(args, filename)
+	slog.Debug("running age", "args", args)
+	cmd := exec.CommandContext(Context, "age", args...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute age --decrypt: %w", err)
+	}
+
+	return output, nil
+}
+
+func (b AgeBackend) Encrypt(filename string, data []byte) error {
+	if b.Identity != "" {
+		return fmt.Errorf("encrypting with an age identity is not supported")
+	}
+
+	slog.Debug("running age", "args", []string{"--encrypt", "--passphrase", "--output", filename})
+	cmd := exec.CommandContext(Context, "age", "--encrypt", "--passphrase", "--output", filename)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to execute age --encrypt: %w", err)
+	}
+
+	if _, err := stdin.Write(data); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	_ = stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("unable to wait for age --encrypt: %w", err)
+	}
+
+	return nil
+}
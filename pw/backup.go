@@ -0,0 +1,181 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup makes the previous version of the password file be kept as a backup every time it is written.
+// Backups are named like the file with the suffix .bak. followed by a timestamp.
+var Backup = true
+
+var ErrNoBackup = errors.New("no backup found")
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+// backupFilename returns the name of a new backup of the file.
+func backupFilename(filename string) string {
+	return filename + ".bak." + time.Now().UTC().Format(backupTimeFormat)
+}
+
+// backups returns the backups of the file, oldest first. Only files named with a valid timestamp are included.
+func backups(filename string) ([]string, error) {
+	matches, err := filepath.Glob(escapeGlob(filename) + ".bak.*")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, match := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(match, filename+".bak.")); err == nil {
+			names = append(names, match)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// storedFiles returns the files the password file is stored in, and which are backed up when it is written: its
+// shares if it is split, otherwise the file itself.
+func storedFiles(filename string) []string {
+	if shares, ok := Splits[filename]; ok {
+		return shares[:]
+	}
+	return []string{filename}
+}
+
+// escapeGlob escapes the glob metacharacters in a path.
+func escapeGlob(path string) string {
+	var escaped []rune
+	for _, c := range path {
+		switch c {
+		case '*', '?', '[', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, c)
+	}
+	return string(escaped)
+}
+
+// backup keeps the current version of the file as a backup, if it exists, and returns the name of the backup,
+// or an empty string if the file does not exist.
+func backup(filename string) (string, error) {
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	backupName := backupFilename(filename)
+	if err := os.Link(filename, backupName); err == nil {
+		slog.Debug("backed up", "file", filename, "backup", backupName)
+		return backupName, nil
+	}
+
+	// Hard links are not supported by all file systems, so fall back to copying
+	src, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("unable to back up %s: %w", filename, err)
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(backupName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FileMode)
+	if err != nil {
+		return "", fmt.Errorf("unable to back up %s: %w", filename, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return "", fmt.Errorf("unable to back up %s: %w", filename, err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("unable to back up %s: %w", filename, err)
+	}
+	slog.Debug("backed up", "file", filename, "backup", backupName)
+	return backupName, nil
+}
+
+// Undo restores the most recent backup of the password file, reverting the last change.
+// The current version is kept as a new backup, so that the undo can be undone.
+// Split password files are not supported, since their shares must be restored together.
+func Undo(filename string) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+	if variable, ok := envVariable(filename); ok {
+		return fmt.Errorf("%w: %s is read from environment variable %s", ErrReadOnly, filename, variable)
+	}
+	if _, ok := Splits[filename]; ok {
+		return fmt.Errorf("undo is not supported for split password file %s", filename)
+	}
+
+	existing, err := backups(filename)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return ErrNoBackup
+	}
+	latest := existing[len(existing)-1]
+
+	if _, err := backup(filename); err != nil {
+		return err
+	}
+
+	if err := os.Rename(latest, filename); err != nil {
+		return fmt.Errorf("unable to restore %s: %w", latest, err)
+	}
+	return nil
+}
+
+// PruneBackups removes all but the keep most recent backups of the password file, or of each of its shares if it
+// is split, and returns the number of backups removed. The password file itself is never removed.
+func PruneBackups(filename string, keep int) (removed int, err error) {
+	if len(filename) == 0 {
+		return 0, fmt.Errorf("filename cannot be empty")
+	}
+	if keep < 0 {
+		return 0, fmt.Errorf("number of backups to keep cannot be negative")
+	}
+
+	for _, file := range storedFiles(filename) {
+		existing, err := backups(file)
+		if err != nil {
+			return removed, err
+		}
+		for len(existing) > keep {
+			if err := os.Remove(existing[0]); err != nil {
+				return removed, fmt.Errorf("unable to remove backup: %w", err)
+			}
+			slog.Debug("removed backup", "file", file, "backup", existing[0])
+			existing = existing[1:]
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// BackupsSize returns the total size in bytes of the backups of the password file, or of its shares if it is split.
+func BackupsSize(filename string) (int64, error) {
+	if len(filename) == 0 {
+		return 0, fmt.Errorf("filename cannot be empty")
+	}
+
+	var size int64
+	for _, file := range storedFiles(filename) {
+		existing, err := backups(file)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range existing {
+			fileInfo, err := os.Stat(name)
+			if err != nil {
+				return 0, err
+			}
+			size += fileInfo.Size()
+		}
+	}
+	return size, nil
+}
@@ -0,0 +1,86 @@
+package pw
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	filename := newTestFile(t)
+	Backup = true
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Undo(filename); !errors.Is(err, ErrNoBackup) {
+		t.Errorf("expected ErrNoBackup, got %v", err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Undo(filename); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Count(filename); err != nil || count != 0 {
+		t.Errorf("counted %d after undo: %v", count, err)
+	}
+	// The undo is undone by undoing again
+	if err := Undo(filename); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Count(filename); err != nil || count != 1 {
+		t.Errorf("counted %d after undoing the undo: %v", count, err)
+	}
+
+	existing, err := backups(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := PruneBackups(filename, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != len(existing)-1 {
+		t.Errorf("removed %d of %d backups, expected to keep 1", removed, len(existing))
+	}
+}
+
+func TestUndoAndInfoUnsupported(t *testing.T) {
+	if err := Undo("env:GOPW_TEST_VAULT_JSON"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly for undo of an env source, got %v", err)
+	}
+	if _, err := Info("env:GOPW_TEST_VAULT_JSON"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly for info of an env source, got %v", err)
+	}
+
+	filename := newTestFile(t)
+	Backup = true
+	dir := filepath.Dir(filename)
+	Splits[filename] = [2]string{filepath.Join(dir, "share1"), filepath.Join(dir, "share2")}
+	t.Cleanup(func() { delete(Splits, filename) })
+	if _, err := Info(filename); !errors.Is(err, ErrPwFileNotFound) {
+		t.Errorf("expected ErrPwFileNotFound for info before init, got %v", err)
+	}
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Undo(filename); err == nil {
+		t.Error("expected an error for undo of a split file")
+	}
+	info, err := Info(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size == 0 || info.ModTime.IsZero() {
+		t.Errorf("got %+v for a split file", info)
+	}
+	size, err := BackupsSize(filename)
+	if err != nil || size == 0 {
+		t.Errorf("got backups size %d of the shares: %v", size, err)
+	}
+}
@@ -0,0 +1,71 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// The BIP-39 English wordlist, see https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki
+//
+//go:embed bip39_english.txt
+var bip39Wordlist string
+
+var bip39Words = sync.OnceValue(func() []string {
+	return strings.Split(strings.TrimSpace(bip39Wordlist), "\n")
+})
+
+// validRecoveryWords reports whether words is a valid number of words in a BIP-39 mnemonic: 12, 15, 18, 21 or 24.
+func validRecoveryWords(words int) bool {
+	return words >= 12 && words <= 24 && words%3 == 0
+}
+
+// GenerateBIP39 generates a BIP-39 mnemonic of the given number of words, 12, 15, 18, 21 or 24, from random
+// entropy of 128 to 256 bits read from crypto/rand, with a checksum from its SHA-256 hash.
+func GenerateBIP39(words int) (string, error) {
+	if !validRecoveryWords(words) {
+		return "", fmt.Errorf("number of words must be 12, 15, 18, 21 or 24")
+	}
+
+	// Each word encodes 11 bits, of which 32 of every 33 bits are entropy and the rest checksum
+	entropyBits := words * 11 * 32 / 33
+	entropy := make([]byte, entropyBits/8)
+	if _, err := cryptorand.Read(entropy); err != nil {
+		return "", fmt.Errorf("unable to generate entropy: %w", err)
+	}
+	return bip39Mnemonic(entropy), nil
+}
+
+// bip39Mnemonic returns the BIP-39 mnemonic encoding the entropy, which must be 16 to 32 bytes in steps of 4.
+func bip39Mnemonic(entropy []byte) string {
+	checksum := sha256.Sum256(entropy)
+	data := append(entropy[:len(entropy):len(entropy)], checksum[0])
+
+	wordlist := bip39Words()
+	mnemonic := make([]string, len(entropy)*8*33/32/11)
+	for i := range mnemonic {
+		index := 0
+		for bit := i * 11; bit < (i+1)*11; bit++ {
+			index = index<<1 | int(data[bit/8]>>(7-bit%8)&1)
+		}
+		mnemonic[i] = wordlist[index]
+	}
+	return strings.Join(mnemonic, " ")
+}
+
+// SetRecoveryPhrase sets the recovery phrase of the password entry with the given name, which must have a valid
+// number of words for a BIP-39 mnemonic, or removes it if phrase is empty.
+func SetRecoveryPhrase(filename string, name string, phrase string) error {
+	words := strings.Fields(phrase)
+	if len(words) > 0 && !validRecoveryWords(len(words)) {
+		return fmt.Errorf("recovery phrase has %d words, must have 12, 15, 18, 21 or 24", len(words))
+	}
+
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		entry.RecoveryPhrase = strings.Join(words, " ")
+		return nil
+	})
+}
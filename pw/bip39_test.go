@@ -0,0 +1,103 @@
+package pw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBIP39Mnemonic(t *testing.T) {
+	// Test vectors from the reference implementation, https://github.com/trezor/python-mnemonic
+	tests := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		},
+		{
+			"80808080808080808080808080808080",
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		},
+		{
+			"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		},
+		{
+			"9e885d952ad362caeb4efe34a8e91bd2",
+			"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		},
+		{
+			"000000000000000000000000000000000000000000000000",
+			strings.Repeat("abandon ", 17) + "agent",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			strings.Repeat("zoo ", 23) + "vote",
+		},
+	}
+	for _, test := range tests {
+		entropy, err := hex.DecodeString(test.entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic := bip39Mnemonic(entropy); mnemonic != test.mnemonic {
+			t.Errorf("mnemonic of %s is %q, expected %q", test.entropy, mnemonic, test.mnemonic)
+		}
+	}
+}
+
+func TestGenerateBIP39(t *testing.T) {
+	wordlist := bip39Words()
+	if len(wordlist) != 2048 {
+		t.Fatalf("wordlist has %d words, expected 2048", len(wordlist))
+	}
+
+	for _, words := range []int{12, 15, 18, 21, 24} {
+		mnemonic, err := GenerateBIP39(words)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := strings.Fields(mnemonic)
+		if len(fields) != words {
+			t.Fatalf("generated %d words, expected %d", len(fields), words)
+		}
+
+		// Decode the words back to bits, and check the checksum
+		bits := make([]byte, 0, words*11)
+		for _, word := range fields {
+			index := slices.Index(wordlist, word)
+			if index < 0 {
+				t.Fatalf("generated %s, which is not in the wordlist", word)
+			}
+			for bit := 10; bit >= 0; bit-- {
+				bits = append(bits, byte(index>>bit&1))
+			}
+		}
+		checksumBits := words * 11 / 33
+		entropy := make([]byte, (len(bits)-checksumBits)/8)
+		for i, bit := range bits[:len(entropy)*8] {
+			entropy[i/8] |= bit << (7 - i%8)
+		}
+		checksum := sha256.Sum256(entropy)
+		for i, bit := range bits[len(entropy)*8:] {
+			if checksum[0]>>(7-i)&1 != bit {
+				t.Errorf("invalid checksum of %q", mnemonic)
+				break
+			}
+		}
+	}
+
+	for _, words := range []int{0, 11, 13, 27} {
+		if _, err := GenerateBIP39(words); err == nil {
+			t.Errorf("expected an error for %d words", words)
+		}
+	}
+}
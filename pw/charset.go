@@ -0,0 +1,32 @@
+package pw
+
+import "strings"
+
+const (
+	// URLSafeChars are the characters which never need to be escaped in a URL.
+	URLSafeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~"
+	// ShellSafeChars are the characters which never need to be quoted in a POSIX shell.
+	ShellSafeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._+,:@%/="
+)
+
+// FilterCharset returns the characters in charset which are also in allowed.
+func FilterCharset(charset string, allowed string) string {
+	var filtered strings.Builder
+	for _, c := range charset {
+		if strings.ContainsRune(allowed, c) {
+			filtered.WriteRune(c)
+		}
+	}
+	return filtered.String()
+}
+
+// ExcludeChars returns the characters in charset which are not in exclude.
+func ExcludeChars(charset string, exclude string) string {
+	var filtered strings.Builder
+	for _, c := range charset {
+		if !strings.ContainsRune(exclude, c) {
+			filtered.WriteRune(c)
+		}
+	}
+	return filtered.String()
+}
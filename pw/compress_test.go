@@ -0,0 +1,66 @@
+package pw
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedRoundTrip(t *testing.T) {
+	filename := newTestFile(t)
+	Compress = true
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := testBackend{}.Decrypt(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		t.Fatalf("not compressed: %q", data)
+	}
+
+	// Compressed files are detected when read, and files written without Compress are not compressed
+	Compress = false
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret" {
+		t.Errorf("got %+v", entry)
+	}
+	if err := Add(filename, PasswordEntry{Name: "other", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err = testBackend{}.Decrypt(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("{")) {
+		t.Errorf("compressed without Compress: %q", data)
+	}
+	if count, err := Count(filename); err != nil || count != 2 {
+		t.Errorf("counted %d: %v", count, err)
+	}
+}
+
+func TestGzipData(t *testing.T) {
+	for _, data := range [][]byte{{}, []byte(`{"version":1,"entries":[]}`), bytes.Repeat([]byte("password"), 1000)} {
+		compressed, err := gzipData(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(compressed, gzipMagic) {
+			t.Errorf("compressed data does not start with the gzip magic: %x", compressed)
+		}
+		decompressed, err := gunzip(compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("decompressed to %q, expected %q", decompressed, data)
+		}
+	}
+}
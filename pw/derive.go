@@ -0,0 +1,58 @@
+package pw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for DerivePassword. Changing them changes all derived passwords.
+const (
+	deriveTime    = 3
+	deriveMemory  = 64 * 1024
+	deriveThreads = 4
+)
+
+// DerivePassword deterministically derives a password of length characters from the charset for a site,
+// from a master secret. The same master secret, site, length and charset always give the same password,
+// so nothing needs to be stored. The master secret and site are run through Argon2id.
+func DerivePassword(master, site string, length int, charset string) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("length must be positive")
+	}
+	if len(master) == 0 {
+		return "", fmt.Errorf("master secret cannot be empty")
+	}
+	if len(site) == 0 {
+		return "", fmt.Errorf("site cannot be empty")
+	}
+	chars := []rune(charset)
+	if len(chars) == 0 {
+		return "", fmt.Errorf("charset cannot be empty")
+	}
+
+	seed := argon2.IDKey([]byte(master), []byte("gopw derive:"+site), deriveTime, deriveMemory, deriveThreads, 32)
+
+	// Expand the seed into a stream of uniform numbers, rejecting those which would bias the mapping onto the charset
+	n := uint64(len(chars))
+	limit := (1 << 32) - (1<<32)%n
+	password := make([]rune, 0, length)
+	var block []byte
+	for counter := uint64(0); len(password) < length; {
+		if len(block) == 0 {
+			mac := hmac.New(sha256.New, seed)
+			_ = binary.Write(mac, binary.BigEndian, counter)
+			block = mac.Sum(nil)
+			counter++
+		}
+		sample := uint64(binary.BigEndian.Uint32(block))
+		block = block[4:]
+		if sample < limit {
+			password = append(password, chars[sample%n])
+		}
+	}
+	return string(password), nil
+}
@@ -0,0 +1,82 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Destroy overwrites the password file and its backups with random bytes, and then removes them, as well as its
+// mirror, or its shares and their backups if it is split.
+// This is best effort, since file systems and storage devices may keep copies of the old content.
+func Destroy(filename string) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	var files []string
+	if shares, ok := Splits[filename]; ok {
+		if !splitExists(filename) {
+			return ErrPwFileNotFound
+		}
+		for _, share := range shares {
+			existing, err := backups(share)
+			if err != nil {
+				return err
+			}
+			files = append(files, existing...)
+			files = append(files, share, previousShare(share))
+		}
+	} else {
+		if _, err := os.Stat(filename); errors.Is(err, fs.ErrNotExist) {
+			return ErrPwFileNotFound
+		}
+		existing, err := backups(filename)
+		if err != nil {
+			return err
+		}
+		files = append(existing, filename)
+	}
+	if mirror := Mirrors[filename]; mirror != "" && mirror != filename {
+		files = append(files, mirror)
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := wipe(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wipe overwrites the file with random bytes, and removes it.
+func wipe(filename string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("unable to wipe %s: %w", filename, err)
+	}
+	stat, err := f.Stat()
+	if err == nil {
+		_, err = io.CopyN(f, cryptorand.Reader, stat.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to wipe %s: %w", filename, err)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		return fmt.Errorf("unable to remove %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// The EFF large wordlist for diceware, see https://www.eff.org/dice
+//
+//go:embed eff_large_wordlist.txt
+var dicewareWordlist string
+
+const dicewareRolls = 5
+
+var dicewareWords = sync.OnceValue(func() map[string]string {
+	words := make(map[string]string, 7776)
+	for _, line := range strings.Split(strings.TrimSpace(dicewareWordlist), "\n") {
+		rolls, word, found := strings.Cut(line, "\t")
+		if found {
+			words[rolls] = word
+		}
+	}
+	return words
+})
+
+// GenerateDiceware generates a diceware passphrase of the given number of words.
+// Each word is selected by five rolls of a die, simulated with crypto/rand.
+// It returns the passphrase and its entropy in bits.
+func GenerateDiceware(words int) (string, float64, error) {
+	if words <= 0 {
+		return "", 0, fmt.Errorf("number of words must be positive")
+	}
+
+	wordlist := dicewareWords()
+	die := big.NewInt(6)
+	phrase := make([]string, words)
+	for i := 0; i < words; i++ {
+		var rolls strings.Builder
+		for j := 0; j < dicewareRolls; j++ {
+			roll, err := cryptorand.Int(cryptorand.Reader, die)
+			if err != nil {
+				return "", 0, err
+			}
+			rolls.WriteByte(byte('1' + roll.Int64()))
+		}
+		word, found := wordlist[rolls.String()]
+		if !found {
+			return "", 0, fmt.Errorf("diceware wordlist has no word for %s", rolls.String())
+		}
+		phrase[i] = word
+	}
+
+	entropy := float64(words) * math.Log2(float64(len(wordlist)))
+	return strings.Join(phrase, " "), entropy, nil
+}
@@ -0,0 +1,123 @@
+package pw
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// untaggedShard is the name of the file in a password directory for entries without tags.
+const untaggedShard = "untagged"
+
+// ShardFilename returns the name of the password file in the password directory dir for entries with the
+// given primary tag, or for entries without tags if tag is empty. The files are named by the tag, with the name of
+// the backend as extension.
+func ShardFilename(dir string, tag string) string {
+	if tag == "" {
+		tag = untaggedShard
+	}
+	return filepath.Join(dir, tag+"."+DefaultBackend.Name())
+}
+
+// shards returns the password files in the password directory dir.
+func shards(dir string) ([]string, error) {
+	if len(dir) == 0 {
+		return nil, fmt.Errorf("directory cannot be empty")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), "."+DefaultBackend.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// ListDir fetches all password entries from all password files in the password directory dir, sorted by name.
+func ListDir(dir string) ([]PasswordEntry, error) {
+	files, err := shards(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []PasswordEntry
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		all = append(all, data...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+	return all, nil
+}
+
+// FindShard returns the password file in the password directory dir with the entry with the given name or alias,
+// and the entry, reading each file only once.
+func FindShard(dir string, name string) (string, *PasswordEntry, error) {
+	files, err := shards(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, entry := range data {
+			if entry.hasName(name) {
+				if err := Unseal(&entry); err != nil {
+					return "", nil, err
+				}
+				return file, &entry, nil
+			}
+		}
+	}
+	return "", nil, ErrPwNotFound
+}
+
+// AddDir adds a new password entry to the password file in the password directory dir for its first tag,
+// creating the file if needed. The name must not be used by an entry in any of the files. Each file is read
+// only once.
+func AddDir(dir string, newEntry PasswordEntry) error {
+	var tag string
+	if len(newEntry.Tags) > 0 {
+		tag = newEntry.Tags[0]
+	}
+	if strings.ContainsAny(tag, `/\`) || tag == "." || tag == ".." {
+		return fmt.Errorf("invalid tag for a password file: %s", tag)
+	}
+	filename := ShardFilename(dir, tag)
+
+	files, err := shards(dir)
+	if err != nil {
+		return err
+	}
+	shard := &document{}
+	for _, file := range files {
+		doc, err := readDocument(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, entry := range doc.Entries {
+			if entry.hasName(newEntry.Name) {
+				return ErrPwAlreadyExists
+			}
+		}
+		if file == filename {
+			shard = doc
+		}
+	}
+
+	return addEntry(filename, shard, newEntry)
+}
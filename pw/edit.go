@@ -0,0 +1,96 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EditJSON returns all password entries in plaintext as an indented JSON array, for editing with ReplaceJSON.
+func EditJSON(filename string) ([]byte, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []PasswordEntry{}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return nil, fmt.Errorf("unable to marshal to JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ReplaceJSON replaces all password entries with the entries in content, as returned by EditJSON and then edited.
+// Entries without an ID or creation time get them, and entries which differ from the entry with the same ID
+// get a new update time.
+func ReplaceJSON(filename string, content []byte) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	entries, err := ParseJSON(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		for _, other := range entries[:i] {
+			if other.hasName(entry.Name) {
+				return fmt.Errorf("%w: %s", ErrPwAlreadyExists, entry.Name)
+			}
+		}
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	if err := unsealAll(doc.Entries); err != nil {
+		return err
+	}
+	old := make(map[string]PasswordEntry, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		if entry.ID != "" {
+			old[entry.ID] = entry
+		}
+	}
+
+	now := time.Now().UTC()
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ID == "" {
+			entry.ID, err = newID()
+			if err != nil {
+				return err
+			}
+		}
+		if entry.CreatedAt == nil {
+			entry.CreatedAt = &now
+		}
+		oldEntry, ok := old[entry.ID]
+		if !ok || !sameEntry(oldEntry, *entry) {
+			entry.UpdatedAt = &now
+		}
+	}
+
+	return write(filename, doc.with(entries))
+}
+
+// sameEntry reports whether the entries have the same content, ignoring the update time.
+func sameEntry(a PasswordEntry, b PasswordEntry) bool {
+	a.UpdatedAt = nil
+	b.UpdatedAt = nil
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
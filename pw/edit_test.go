@@ -0,0 +1,117 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestReplaceJSON(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"changed", "unchanged", "removed"} {
+		if err := Add(filename, PasswordEntry{Name: name, Password: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := List(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := EditJSON(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []PasswordEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		t.Fatal(err)
+	}
+	var edited []PasswordEntry
+	for _, entry := range entries {
+		switch entry.Name {
+		case "changed":
+			entry.Password = "other"
+			edited = append(edited, entry)
+		case "unchanged":
+			edited = append(edited, entry)
+		}
+	}
+	edited = append(edited, PasswordEntry{Name: "added", Password: "secret"})
+	content, err = json.Marshal(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceJSON(filename, content); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := List(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 3 {
+		t.Fatalf("got %+v", after)
+	}
+	byName := make(map[string]PasswordEntry)
+	for _, entry := range before {
+		byName[entry.Name] = entry
+	}
+	for _, entry := range after {
+		switch entry.Name {
+		case "changed":
+			if entry.Password != "other" || entry.ID != byName["changed"].ID || entry.UpdatedAt.Equal(*byName["changed"].UpdatedAt) {
+				t.Errorf("got %+v, changed from %+v", entry, byName["changed"])
+			}
+		case "unchanged":
+			if !entry.UpdatedAt.Equal(*byName["unchanged"].UpdatedAt) {
+				t.Errorf("got %+v, unchanged from %+v", entry, byName["unchanged"])
+			}
+		case "added":
+			if entry.ID == "" || entry.CreatedAt == nil || entry.UpdatedAt == nil {
+				t.Errorf("got %+v, without ID or timestamps", entry)
+			}
+		default:
+			t.Errorf("got unexpected entry %+v", entry)
+		}
+	}
+}
+
+func TestReplaceJSONInvalid(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	original, err := EditJSON(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{
+		`[{"name":"a"},{"name":"a"}]`,
+		`[{"name":"a","aliases":["b"]},{"name":"b"}]`,
+		`[{"name":""}]`,
+		`[{"name":"a"`,
+	} {
+		if err := ReplaceJSON(filename, []byte(content)); err == nil {
+			t.Errorf("expected an error replacing with %s", content)
+		}
+	}
+	if err := ReplaceJSON(filename, []byte(`[{"name":"a"},{"name":"a"}]`)); !errors.Is(err, ErrPwAlreadyExists) {
+		t.Errorf("expected ErrPwAlreadyExists, got %v", err)
+	}
+
+	content, err := EditJSON(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, original) {
+		t.Errorf("changed by failed replacing: %s", content)
+	}
+}
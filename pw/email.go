@@ -0,0 +1,47 @@
+package pw
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// normalizeEmail returns the username with the domain part lowercased, if it is an email address.
+func normalizeEmail(username string) string {
+	if !emailPattern.MatchString(username) {
+		return username
+	}
+	at := strings.LastIndexByte(username, '@')
+	return username[:at] + strings.ToLower(username[at:])
+}
+
+// NormalizeEmails finds the entries with a username which is an email address with uppercase characters in the
+// domain part, and returns their names. If apply is true, the domain parts are also lowercased.
+func NormalizeEmails(filename string, apply bool) ([]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return nil, err
+	}
+	data := doc.Entries
+
+	var names []string
+	for i, entry := range data {
+		if normalized := normalizeEmail(entry.Username); normalized != entry.Username {
+			names = append(names, entry.Name)
+			data[i].Username = normalized
+		}
+	}
+
+	if apply && len(names) > 0 {
+		if err := write(filename, doc.with(data)); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
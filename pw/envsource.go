@@ -0,0 +1,28 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix is the prefix of filenames referring to an environment variable with the decrypted JSON content of a
+// password file, e.g. env:GOPW_VAULT_JSON. Such password files are not encrypted, and cannot be written.
+const envPrefix = "env:"
+
+var ErrReadOnly = errors.New("password file is read-only")
+
+// envVariable returns the name of the environment variable a filename refers to, if it does.
+func envVariable(filename string) (string, bool) {
+	return strings.CutPrefix(filename, envPrefix)
+}
+
+// readEnv returns the content of the environment variable, which must be set.
+func readEnv(variable string) ([]byte, error) {
+	content, ok := os.LookupEnv(variable)
+	if !ok {
+		return nil, fmt.Errorf("%w: environment variable %s is not set", ErrPwFileNotFound, variable)
+	}
+	return []byte(content), nil
+}
@@ -0,0 +1,40 @@
+package pw
+
+import (
+	"log/slog"
+	"time"
+)
+
+// PruneExpired makes reading a password file with expired entries also write it without them.
+// Otherwise, expired entries are only left out when reading, and removed the next time the file is written.
+var PruneExpired bool
+
+// Expired reports whether the entry has an expiry time which has passed at now.
+func (e PasswordEntry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// withoutExpired returns the entries which have not expired, and the number of expired entries.
+func withoutExpired(data []PasswordEntry) ([]PasswordEntry, int) {
+	now := time.Now()
+	valid := make([]PasswordEntry, 0, len(data))
+	for _, entry := range data {
+		if !entry.Expired(now) {
+			valid = append(valid, entry)
+		}
+	}
+	return valid, len(data) - len(valid)
+}
+
+// pruneExpired leaves out the expired entries of the document read from the password file, and writes the file
+// without them if PruneExpired is set.
+func pruneExpired(filename string, doc *document) *document {
+	valid, expired := withoutExpired(doc.Entries)
+	doc = doc.with(valid)
+	if expired > 0 && PruneExpired {
+		if err := write(filename, doc); err != nil {
+			slog.Warn("unable to remove expired passwords", "file", filename, "error", err)
+		}
+	}
+	return doc
+}
@@ -0,0 +1,155 @@
+package pw
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportJSONL writes all password entries in plaintext to w as JSON Lines, one JSON object per line.
+func ExportJSONL(filename string, w io.Writer) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range data {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportEntry writes the password entry with the given name or alias in plaintext to w as indented JSON,
+// or with secrets replaced like ExportRedacted if redact is set.
+func ExportEntry(filename string, name string, redact bool, w io.Writer) error {
+	entry, err := Get(filename, name)
+	if err != nil {
+		return err
+	}
+	if redact {
+		*entry = redactEntry(*entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entry)
+}
+
+// redacted replaces secrets in exports.
+const redacted = "****"
+
+// ExportRedacted writes all password entries to w as JSON Lines like ExportJSONL, but with passwords,
+// TOTP secrets and private keys replaced by "****", so that it can be shared without leaking secrets.
+func ExportRedacted(filename string, w io.Writer) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range data {
+		if err := encoder.Encode(redactEntry(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactEntry returns a copy of the entry with all secrets replaced.
+func redactEntry(entry PasswordEntry) PasswordEntry {
+	if entry.Password != "" || entry.Sealed != "" {
+		entry.Password = redacted
+	}
+	entry.Sealed = ""
+	if entry.PrivateKey != "" {
+		entry.PrivateKey = redacted
+	}
+	if entry.RecoveryPhrase != "" {
+		entry.RecoveryPhrase = redacted
+	}
+	if entry.Notes != "" {
+		entry.Notes = redacted
+	}
+	if entry.TOTP != nil {
+		totp := *entry.TOTP
+		totp.Secret = redacted
+		entry.TOTP = &totp
+	}
+	accounts := make([]Account, len(entry.Accounts))
+	for i, account := range entry.Accounts {
+		accounts[i] = Account{Username: account.Username, Password: redacted}
+	}
+	if len(accounts) > 0 {
+		entry.Accounts = accounts
+	}
+	return entry
+}
+
+// ExportEnv writes the passwords of the entries matching filter in plaintext to w in .env format,
+// as NAME=password lines. Names are uppercased with characters other than letters and digits replaced by
+// underscore. Passwords with special characters are double-quoted and escaped.
+func ExportEnv(filename string, filter func(PasswordEntry) bool, w io.Writer) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range data {
+		if filter != nil && !filter(entry) {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", envName(entry.Name), envValue(entry.Password)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// envName converts an entry name to an environment variable name.
+func envName(name string) string {
+	var envName strings.Builder
+	for _, c := range strings.ToUpper(name) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			envName.WriteRune(c)
+		} else {
+			envName.WriteByte('_')
+		}
+	}
+	if envName.Len() == 0 || (name[0] >= '0' && name[0] <= '9') {
+		return "_" + envName.String()
+	}
+	return envName.String()
+}
+
+// envValue quotes and escapes a value for a .env file if needed.
+func envValue(value string) string {
+	safe := true
+	for _, c := range value {
+		if !strings.ContainsRune(ShellSafeChars, c) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,36 @@
+package pw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strings"
+)
+
+// hintWords are the words of password hints.
+var hintWords = [...]string{
+	"apple", "bear", "cloud", "drum", "eagle", "flute", "grape", "harbor",
+	"island", "jungle", "kettle", "lemon", "maple", "needle", "orbit", "pepper",
+}
+
+// hintKey is the HMAC key of password hints.
+const hintKey = "gopw password hint"
+
+// hintLength is the number of words in a password hint.
+const hintLength = 3
+
+// PasswordHint returns a mnemonic hint for the password, with three words derived from an HMAC-SHA256 of the whole
+// password, for checking a recalled password against.
+//
+// The same password always gets the same hint. Since no word depends on any single character, the hint does not
+// reveal the length, character classes or any character of the password. It is low-entropy though, with 12 bits,
+// so the strength of the password is lowered by up to that much if the hint is known.
+func PasswordHint(password string) string {
+	mac := hmac.New(sha256.New, []byte(hintKey))
+	mac.Write([]byte(password))
+	sum := mac.Sum(nil)
+	words := make([]string, hintLength)
+	for i := range words {
+		words[i] = hintWords[int(sum[i])%len(hintWords)]
+	}
+	return strings.Join(words, " ")
+}
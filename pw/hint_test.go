@@ -0,0 +1,33 @@
+package pw
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHint(t *testing.T) {
+	passwords := []string{"", "a", "correct horse battery staple", "Xk9#mP2$vL7@qR4!", "Xk9#mP2$vL7@qR4?"}
+	hints := make(map[string]string)
+	for _, password := range passwords {
+		hint := PasswordHint(password)
+		if hint != PasswordHint(password) {
+			t.Errorf("hint of %q is not deterministic", password)
+		}
+		words := strings.Fields(hint)
+		if len(words) != hintLength {
+			t.Errorf("hint of %q is %q, expected %d words", password, hint, hintLength)
+		}
+		for _, word := range words {
+			if !slices.Contains(hintWords[:], word) {
+				t.Errorf("hint of %q has %s, which is not a hint word", password, word)
+			}
+		}
+		hints[password] = hint
+	}
+
+	// The hint depends on the whole password, not on each character
+	if hints["Xk9#mP2$vL7@qR4!"] == hints["Xk9#mP2$vL7@qR4?"] {
+		t.Error("passwords differing in the last character got the same hint")
+	}
+}
@@ -0,0 +1,35 @@
+package pw
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// PreWriteHook is a command to run before the password file is written, if set.
+	// It is given the filename as its last argument. If it fails, the file is not written.
+	PreWriteHook string
+	// PostWriteHook is a command to run after the password file has been written successfully, if set.
+	// It is given the filename as its last argument. A failure is reported, but does not undo the write.
+	PostWriteHook string
+)
+
+// runHook runs the hook command, consisting of space separated arguments, with the filename appended.
+func runHook(hook string, filename string) error {
+	args := strings.Fields(hook)
+	if len(args) == 0 {
+		return nil
+	}
+
+	slog.Debug("running hook", "command", args[0], "file", filename)
+	cmd := exec.CommandContext(Context, args[0], append(args[1:], filename)...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook %s failed: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+)
+
+// newID returns a new random UUID (version 4), to identify an entry regardless of its name.
+func newID() (string, error) {
+	var uuid [16]byte
+	if _, err := cryptorand.Read(uuid[:]); err != nil {
+		return "", fmt.Errorf("unable to generate ID: %w", err)
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+// GetByID fetches a password entry by its ID.
+func GetByID(filename string, id string) (*PasswordEntry, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	if len(id) == 0 {
+		return nil, fmt.Errorf("ID cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range data {
+		if entry.ID == id {
+			if err := Unseal(&entry); err != nil {
+				return nil, err
+			}
+			return &entry, nil
+		}
+	}
+	return nil, ErrPwNotFound
+}
+
+// ModifyByID modifies the password entry with the ID in place with fn, like Modify.
+func ModifyByID(filename string, id string, fn func(entry *PasswordEntry) error) error {
+	if len(id) == 0 {
+		return fmt.Errorf("ID cannot be empty")
+	}
+
+	return modifyWhere(filename, func(entry PasswordEntry) bool {
+		return entry.ID == id
+	}, fn)
+}
+
+// RemoveByID removes the password entry with the ID.
+func RemoveByID(filename string, id string) error {
+	if len(id) == 0 {
+		return fmt.Errorf("ID cannot be empty")
+	}
+
+	return removeWhere(filename, func(entry PasswordEntry) bool {
+		return entry.ID == id
+	})
+}
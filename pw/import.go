@@ -0,0 +1,270 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SkippedRow is a row of an import file which was not imported.
+type SkippedRow struct {
+	// Row is the row number in the file, counting the header as row 1.
+	Row    int
+	Reason string
+}
+
+// keePassColumns maps the column names of KeePass 1.x and 2.x CSV exports to entry fields.
+var keePassColumns = map[string]string{
+	"account":    "name",
+	"title":      "name",
+	"login name": "username",
+	"username":   "username",
+	"user name":  "username",
+	"password":   "password",
+	"web site":   "url",
+	"url":        "url",
+	"group":      "group",
+}
+
+// csvColumns maps the column names of generic CSV files to entry fields.
+var csvColumns = map[string]string{
+	"name":     "name",
+	"username": "username",
+	"password": "password",
+	"url":      "url",
+}
+
+// ParseKeePassCSV parses a CSV export from KeePass, with a header row naming the columns, either the
+// KeePass 1.x columns Account, Login Name, Password, Web Site and Comments, or the KeePass 2.x columns
+// Group, Title, Username, Password and URL. The group, if any, becomes a tag. Comments and notes are not
+// imported. Rows without a name or a password, and rows repeating the name of an earlier row, are skipped.
+func ParseKeePassCSV(r io.Reader) ([]PasswordEntry, []SkippedRow, error) {
+	return parseCSV(r, keePassColumns, "KeePass CSV file")
+}
+
+// ParseCSV parses a CSV file with a header row naming the columns name, username, password and url.
+// Rows without a name or a password, and rows repeating the name of an earlier row, are skipped.
+func ParseCSV(r io.Reader) ([]PasswordEntry, []SkippedRow, error) {
+	return parseCSV(r, csvColumns, "CSV file")
+}
+
+// parseCSV parses a CSV file with a header row with column names mapped to entry fields by columnNames.
+func parseCSV(r io.Reader, columnNames map[string]string, format string) ([]PasswordEntry, []SkippedRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("empty %s", format)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", format, err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		field, ok := columnNames[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))]
+		if _, exists := columns[field]; ok && !exists {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, nil, fmt.Errorf("invalid %s: no name column", format)
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, nil, fmt.Errorf("invalid %s: no password column", format)
+	}
+
+	var entries []PasswordEntry
+	var skipped []SkippedRow
+	seen := make(map[string]bool)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", format, err)
+		}
+		if len(record) != len(header) {
+			skipped = append(skipped, SkippedRow{row, fmt.Sprintf("has %d columns, expected %d", len(record), len(header))})
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entry := PasswordEntry{
+			Name:     field("name"),
+			Username: field("username"),
+			// Passwords are not trimmed, since spaces may be significant
+			Password: record[columns["password"]],
+			URL:      field("url"),
+		}
+		if group := field("group"); group != "" {
+			entry.Tags = []string{group}
+		}
+
+		switch {
+		case entry.Name == "":
+			skipped = append(skipped, SkippedRow{row, "no name"})
+		case entry.Password == "":
+			skipped = append(skipped, SkippedRow{row, fmt.Sprintf("no password for '%s'", entry.Name)})
+		case seen[entry.Name]:
+			skipped = append(skipped, SkippedRow{row, fmt.Sprintf("duplicate name '%s'", entry.Name)})
+		default:
+			seen[entry.Name] = true
+			entries = append(entries, entry)
+		}
+	}
+	return entries, skipped, nil
+}
+
+// ParseJSON parses password entries as either a JSON array or JSON Lines, as written by ExportJSONL.
+func ParseJSON(r io.Reader) ([]PasswordEntry, error) {
+	decoder := json.NewDecoder(r)
+	var entries []PasswordEntry
+	for {
+		var value json.RawMessage
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if bytes.HasPrefix(value, []byte("[")) {
+			var array []PasswordEntry
+			if err := json.Unmarshal(value, &array); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			entries = append(entries, array...)
+		} else {
+			var entry PasswordEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("entry %d has no name", i+1)
+		}
+		if entry.Sealed != "" {
+			return nil, fmt.Errorf("entry %s is sealed", entry.Name)
+		}
+	}
+	return entries, nil
+}
+
+// ImportReport returns the names of the entries which Import would find conflicting, since an entry with that
+// name or alias already exists, and the names of the new entries, without writing anything.
+func ImportReport(filename string, entries []PasswordEntry) (conflicts []string, added []string, err error) {
+	if len(filename) == 0 {
+		return nil, nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, newEntry := range entries {
+		if entryIndex(data, newEntry.Name) >= 0 {
+			conflicts = append(conflicts, newEntry.Name)
+		} else {
+			added = append(added, newEntry.Name)
+		}
+	}
+	return conflicts, added, nil
+}
+
+// Import adds the entries in a single write, and returns the names of the conflicting entries, since an entry
+// with that name or alias already exists. Conflicting entries are replaced if overwrite is set, otherwise they
+// are not imported. It is an error if the entries have the same name as each other, or if an alias of an imported
+// entry is the name or alias of another entry.
+func Import(filename string, entries []PasswordEntry, overwrite bool) ([]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	for i, entry := range entries {
+		for _, other := range entries[:i] {
+			if other.hasName(entry.Name) {
+				return nil, fmt.Errorf("%w: %s", ErrPwAlreadyExists, entry.Name)
+			}
+		}
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return nil, err
+	}
+	data := doc.Entries
+
+	now := time.Now().UTC()
+	var conflicts []string
+	changed := 0
+	for _, newEntry := range entries {
+		newEntry.CreatedAt = &now
+		newEntry.UpdatedAt = &now
+		i := entryIndex(data, newEntry.Name)
+		if i >= 0 {
+			conflicts = append(conflicts, newEntry.Name)
+			if !overwrite {
+				continue
+			}
+			if data[i].CreatedAt != nil {
+				newEntry.CreatedAt = data[i].CreatedAt
+			}
+			if data[i].ID != "" {
+				newEntry.ID = data[i].ID
+			}
+		}
+		if newEntry.ID == "" {
+			newEntry.ID, err = newID()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if i >= 0 {
+			data[i] = newEntry
+		} else {
+			data = append(data, newEntry)
+		}
+		changed++
+	}
+
+	if changed == 0 {
+		return conflicts, nil
+	}
+	// The imported entries are only looked up by name above, so their aliases may still collide
+	for i, entry := range data {
+		for _, other := range data[:i] {
+			for _, name := range append([]string{entry.Name}, entry.Aliases...) {
+				if other.hasName(name) {
+					return nil, fmt.Errorf("%w: %s", ErrPwAlreadyExists, name)
+				}
+			}
+		}
+	}
+	return conflicts, write(filename, doc.with(data))
+}
+
+// entryIndex returns the index of the entry with the given name or alias in data, or -1 if there is none.
+func entryIndex(data []PasswordEntry, name string) int {
+	for i, entry := range data {
+		if entry.hasName(name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,114 @@
+package pw
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "old", Aliases: []string{"ex"}}); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []PasswordEntry{{Name: "ex", Password: "new"}, {Name: "other", Password: "other"}}
+	conflicts, added, err := ImportReport(filename, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(conflicts, []string{"ex"}) || !slices.Equal(added, []string{"other"}) {
+		t.Errorf("reported conflicts %v and added %v", conflicts, added)
+	}
+
+	conflicts, err = Import(filename, entries, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(conflicts, []string{"ex"}) {
+		t.Errorf("got conflicts %v", conflicts)
+	}
+	if entry, err := Get(filename, "example"); err != nil || entry.Password != "old" {
+		t.Errorf("conflicting entry replaced without overwrite: %+v, %v", entry, err)
+	}
+	if entry, err := Get(filename, "other"); err != nil || entry.Password != "other" || entry.ID == "" {
+		t.Errorf("entry not imported: %+v, %v", entry, err)
+	}
+
+	// Overwriting keeps the ID and creation time of the replaced entry
+	if _, err := Import(filename, []PasswordEntry{{Name: "example", Password: "new"}}, true); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "new" || entry.ID != existing.ID || !entry.CreatedAt.Equal(*existing.CreatedAt) {
+		t.Errorf("got %+v after overwriting %+v", entry, existing)
+	}
+}
+
+func TestImportCollisions(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	collisions := map[string][]PasswordEntry{
+		"same name":                   {{Name: "new"}, {Name: "new"}},
+		"name as alias":               {{Name: "new", Aliases: []string{"other"}}, {Name: "other"}},
+		"alias as name":               {{Name: "new"}, {Name: "other", Aliases: []string{"new"}}},
+		"alias as existing name":      {{Name: "new", Aliases: []string{"example"}}},
+		"alias as other import alias": {{Name: "new", Aliases: []string{"both"}}, {Name: "other", Aliases: []string{"both"}}},
+	}
+	for name, entries := range collisions {
+		for _, overwrite := range []bool{false, true} {
+			if _, err := Import(filename, entries, overwrite); !errors.Is(err, ErrPwAlreadyExists) {
+				t.Errorf("expected ErrPwAlreadyExists importing with %s, got %v", name, err)
+			}
+		}
+	}
+	if count, err := Count(filename); err != nil || count != 1 {
+		t.Errorf("counted %d after failed imports: %v", count, err)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	csv := "\ufeffName,Username,Password,URL\n" +
+		"example,alice,secret,https://example.com\n" +
+		"spaces,bob, secret with spaces ,\n" +
+		",carol,secret,\n" +
+		"nopassword,dave,,\n" +
+		"example,erin,other,\n" +
+		"short,frank\n"
+	entries, skipped, err := ParseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "example" || entries[0].URL != "https://example.com" ||
+		entries[1].Password != " secret with spaces " {
+		t.Errorf("parsed %+v", entries)
+	}
+	var rows []int
+	for _, row := range skipped {
+		rows = append(rows, row.Row)
+	}
+	if !slices.Equal(rows, []int{4, 5, 6, 7}) {
+		t.Errorf("skipped %+v", skipped)
+	}
+
+	if _, _, err := ParseCSV(strings.NewReader("name,username\nexample,alice\n")); err == nil {
+		t.Error("expected an error without a password column")
+	}
+}
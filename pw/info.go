@@ -0,0 +1,82 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileInfo is metadata about a password file, available without decrypting it.
+type FileInfo struct {
+	// Path is the absolute path of the file.
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// Backend is the name of the backend used for the file.
+	Backend string
+	// Sealed is true if the file is sealed, see Seal.
+	Sealed bool
+}
+
+// Info returns metadata about the password file, without decrypting it. For a split password file, the path is
+// the one the shares are split from, and the size and modification time are those of the shares.
+func Info(filename string) (*FileInfo, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	if variable, ok := envVariable(filename); ok {
+		return nil, fmt.Errorf("%w: %s is read from environment variable %s", ErrReadOnly, filename, variable)
+	}
+
+	if _, ok := Splits[filename]; ok && !splitExists(filename) {
+		return nil, ErrPwFileNotFound
+	}
+
+	var size int64
+	var modTime time.Time
+	for _, file := range storedFiles(filename) {
+		stat, err := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			if _, ok := Splits[filename]; ok {
+				return nil, fmt.Errorf("%w: %s", ErrShareMissing, file)
+			}
+			return nil, ErrPwFileNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Both shares are as large as the encrypted file
+		size = stat.Size()
+		if stat.ModTime().After(modTime) {
+			modTime = stat.ModTime()
+		}
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileInfo{
+		Path:    path,
+		Size:    size,
+		ModTime: modTime,
+		Backend: DefaultBackend.Name(),
+		Sealed:  fileIsSealed(filename),
+	}, nil
+}
+
+// Count returns the number of entries in the password file.
+func Count(filename string) (int, error) {
+	if len(filename) == 0 {
+		return 0, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
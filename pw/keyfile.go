@@ -0,0 +1,24 @@
+package pw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// KeyfilePassphrase derives a passphrase from the contents of a key file, to use instead of a typed passphrase.
+// If passphrase is not empty, it is prepended, so that both the key file and the passphrase are required.
+func KeyfilePassphrase(keyfile string, passphrase string) (string, error) {
+	key, err := os.ReadFile(keyfile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read key file: %w", err)
+	}
+	if len(key) == 0 {
+		return "", fmt.Errorf("key file %s is empty", keyfile)
+	}
+
+	// Hash the key, since it may be binary while scrypt reads the passphrase as a line of text
+	hash := sha256.Sum256(key)
+	return passphrase + hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,32 @@
+package pw
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Layouts are easy typing charsets, restricted to characters in the same zone of a QWERTY keyboard, or on
+// the first layer of a phone keyboard.
+var Layouts = map[string]string{
+	"numeric-top-row": "1234567890",
+	"qwerty-top-row":  "qwertyuiop",
+	"qwerty-home-row": "asdfghjkl",
+	"left-hand":       "12345qwertasdfgzxcvb",
+	"right-hand":      "67890yuiophjklnm",
+	"phone-lower":     "abcdefghijklmnopqrstuvwxyz",
+}
+
+// LayoutCharset returns the charset of the layout with the given name in Layouts.
+func LayoutCharset(name string) (string, error) {
+	charset, ok := Layouts[name]
+	if !ok {
+		names := make([]string, 0, len(Layouts))
+		for layout := range Layouts {
+			names = append(names, layout)
+		}
+		slices.Sort(names)
+		return "", fmt.Errorf("unknown layout: %s, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return charset, nil
+}
@@ -0,0 +1,45 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// GenerateLuhn generates a random string of length digits, where the last digit is a check digit
+// making it pass the Luhn check, like credit card numbers. Intended for test data.
+func GenerateLuhn(length int) (string, error) {
+	if length < 2 {
+		return "", fmt.Errorf("length must be at least 2")
+	}
+
+	digits := make([]byte, length)
+	ten := big.NewInt(10)
+	for i := 0; i < length-1; i++ {
+		digit, err := cryptorand.Int(cryptorand.Reader, ten)
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + digit.Int64())
+	}
+	digits[length-1] = '0' + luhnCheckDigit(digits[:length-1])
+
+	return string(digits), nil
+}
+
+// luhnCheckDigit computes the Luhn check digit to append to the digits.
+func luhnCheckDigit(digits []byte) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		// Every second digit from the right, starting with the one next to the check digit, is doubled
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte((10 - sum%10) % 10)
+}
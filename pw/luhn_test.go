@@ -0,0 +1,59 @@
+package pw
+
+import (
+	"testing"
+)
+
+// luhnValid reports whether the number passes the Luhn check, computed independently of luhnCheckDigit.
+func luhnValid(number string) bool {
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func TestLuhnCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		check  byte
+	}{
+		{"7992739871", 3},
+		{"453201511283036", 6},
+		{"0", 0},
+		{"1", 8},
+		{"000000000", 0},
+		{"37828224631000", 5},
+	}
+	for _, test := range tests {
+		if check := luhnCheckDigit([]byte(test.digits)); check != test.check {
+			t.Errorf("check digit of %s is %d, expected %d", test.digits, check, test.check)
+		}
+	}
+}
+
+func TestGenerateLuhn(t *testing.T) {
+	for length := 2; length <= 20; length++ {
+		number, err := GenerateLuhn(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(number) != length {
+			t.Errorf("generated %s, expected length %d", number, length)
+		}
+		if !luhnValid(number) {
+			t.Errorf("generated %s, which does not pass the Luhn check", number)
+		}
+	}
+
+	if _, err := GenerateLuhn(1); err == nil {
+		t.Error("expected an error for length 1")
+	}
+}
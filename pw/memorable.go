@@ -0,0 +1,86 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memorableSymbols are the symbols to choose from in memorable passwords.
+const memorableSymbols = "!#$%&*+-=?@"
+
+// memorableNumbers is the number of numbers to choose from in memorable passwords, 0 to 99.
+const memorableNumbers = 100
+
+// MemorableOptions configures GenerateMemorable.
+type MemorableOptions struct {
+	// Words is the number of words.
+	Words int
+	// Number makes a number from 0 to 99 be included after the first word.
+	Number bool
+	// Symbol makes a symbol be included after the first word, and the number if any.
+	Symbol bool
+}
+
+// Entropy returns the entropy in bits of memorable passwords generated with the options.
+func (o MemorableOptions) Entropy() float64 {
+	entropy := float64(o.Words) * math.Log2(float64(len(memorableWords())))
+	if o.Number {
+		entropy += math.Log2(memorableNumbers)
+	}
+	if o.Symbol {
+		entropy += math.Log2(float64(len(memorableSymbols)))
+	}
+	return entropy
+}
+
+var memorableWords = sync.OnceValue(func() []string {
+	words := make([]string, 0, len(dicewareWords()))
+	for _, word := range dicewareWords() {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+})
+
+// GenerateMemorable generates a password of capitalized words from the diceware wordlist, optionally with a
+// number and a symbol after the first word, like Tiger42!Horse. It is easier to remember than a random password,
+// but has less entropy for its length, see MemorableOptions.Entropy.
+func GenerateMemorable(opts MemorableOptions) (string, error) {
+	if opts.Words <= 0 {
+		return "", fmt.Errorf("number of words must be positive")
+	}
+
+	words := memorableWords()
+	var password strings.Builder
+	for i := 0; i < opts.Words; i++ {
+		index, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(words))))
+		if err != nil {
+			return "", err
+		}
+		word := words[index.Int64()]
+		password.WriteString(strings.ToUpper(word[:1]) + word[1:])
+
+		if i == 0 {
+			if opts.Number {
+				number, err := cryptorand.Int(cryptorand.Reader, big.NewInt(memorableNumbers))
+				if err != nil {
+					return "", err
+				}
+				password.WriteString(number.String())
+			}
+			if opts.Symbol {
+				symbol, err := randomElement([]rune(memorableSymbols))
+				if err != nil {
+					return "", err
+				}
+				password.WriteRune(symbol)
+			}
+		}
+	}
+	return password.String(), nil
+}
@@ -0,0 +1,66 @@
+package pw
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Mirrors maps password files to mirror files. Every time a password file is written, its mirror file is updated
+// with a copy of it, and if the password file cannot be read, the mirror file is read instead.
+var Mirrors = map[string]string{}
+
+// readMirror reads the mirror file of the password file, if it has one, after failing to read the password file
+// with readErr. If the mirror file cannot be read either, readErr is returned.
+func readMirror(filename string, readErr error) (*document, error) {
+	mirror := Mirrors[filename]
+	if mirror == "" || mirror == filename {
+		return nil, readErr
+	}
+
+	doc, err := readFile(mirror)
+	if err != nil {
+		return nil, readErr
+	}
+	slog.Warn("unable to read password file, using mirror", "file", filename, "error", readErr, "mirror", mirror)
+	return doc, nil
+}
+
+// updateMirror copies the password file to its mirror file, if it has one.
+func updateMirror(filename string) error {
+	mirror := Mirrors[filename]
+	if mirror == "" || mirror == filename {
+		return nil
+	}
+
+	src, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("unable to update mirror %s: %w", mirror, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(mirror), filepath.Base(mirror)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("unable to update mirror %s: %w", mirror, err)
+	}
+	tmpFilename := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpFilename) }()
+
+	_, err = io.Copy(tmpFile, src)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFilename, FileMode)
+	}
+	if err == nil {
+		err = os.Rename(tmpFilename, mirror)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update mirror %s: %w", mirror, err)
+	}
+	slog.Debug("updated mirror", "file", filename, "mirror", mirror)
+	return nil
+}
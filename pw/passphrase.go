@@ -0,0 +1,35 @@
+package pw
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// CommandPassphrase runs the command, consisting of space separated arguments, and returns the first line of its
+// output as the passphrase, for getting it from a hardware token or another password manager.
+// The stderr of the command is passed through.
+func CommandPassphrase(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("passphrase command cannot be empty")
+	}
+
+	slog.Debug("running passphrase command", "command", args[0])
+	cmd := exec.CommandContext(Context, args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("passphrase command %s failed: %w", args[0], err)
+	}
+
+	passphrase, _, _ := strings.Cut(string(output), "\n")
+	passphrase = strings.TrimSuffix(passphrase, "\r")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase command %s output no passphrase", args[0])
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,35 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+)
+
+// StrictPerms makes reading a password file with insecure permissions fail, instead of just logging a warning.
+var StrictPerms bool
+
+var ErrInsecurePermissions = errors.New("password file is accessible by group or others")
+
+// checkPermissions warns, or fails if StrictPerms is set, if the password file is accessible by group or
+// others beyond what FileMode allows. Unix permissions do not apply on Windows, so nothing is checked there.
+func checkPermissions(filename string, fileInfo os.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	excess := fileInfo.Mode().Perm() &^ FileMode & 0077
+	if excess == 0 {
+		return nil
+	}
+
+	if StrictPerms {
+		return fmt.Errorf("%w: %s has mode %04o, fix with: chmod %o %s",
+			ErrInsecurePermissions, filename, fileInfo.Mode().Perm(), FileMode, filename)
+	}
+	slog.Warn("password file is accessible by group or others", "file", filename,
+		"mode", fmt.Sprintf("%04o", fileInfo.Mode().Perm()), "fix", fmt.Sprintf("chmod %o %s", FileMode, filename))
+	return nil
+}
@@ -0,0 +1,212 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Policy is a set of rules for generating a password, which can be stored with an entry
+// to generate a compliant password again when rotating it.
+type Policy struct {
+	// MinLength is the minimum password length.
+	MinLength int `json:"minLength"`
+	// MaxLength is the maximum password length, if not zero. Passwords are generated with this length if set,
+	// otherwise with MinLength.
+	MaxLength int `json:"maxLength,omitempty"`
+	// Charset is the characters to generate the password from.
+	Charset string `json:"charset"`
+	// Exclude is characters to remove from Charset.
+	Exclude   string `json:"exclude,omitempty"`
+	MinLower  int    `json:"minLower,omitempty"`
+	MinUpper  int    `json:"minUpper,omitempty"`
+	MinDigit  int    `json:"minDigit,omitempty"`
+	MinSymbol int    `json:"minSymbol,omitempty"`
+}
+
+// characterClass is a class of characters which a policy can require a minimum count of.
+type characterClass struct {
+	name     string
+	contains func(r rune) bool
+}
+
+var (
+	classLower  = characterClass{"lower", func(r rune) bool { return r < unicode.MaxASCII && unicode.IsLower(r) }}
+	classUpper  = characterClass{"upper", func(r rune) bool { return r < unicode.MaxASCII && unicode.IsUpper(r) }}
+	classDigit  = characterClass{"digit", func(r rune) bool { return r >= '0' && r <= '9' }}
+	classSymbol = characterClass{"symbol", func(r rune) bool {
+		return !classLower.contains(r) && !classUpper.contains(r) && !classDigit.contains(r)
+	}}
+)
+
+// filter returns the characters in charset belonging to the class.
+func (c characterClass) filter(charset string) string {
+	var filtered strings.Builder
+	for _, r := range charset {
+		if c.contains(r) {
+			filtered.WriteRune(r)
+		}
+	}
+	return filtered.String()
+}
+
+// EffectiveCharset returns the characters to generate passwords from, with the excluded characters removed.
+func (p Policy) EffectiveCharset() string {
+	return ExcludeChars(p.Charset, p.Exclude)
+}
+
+// length returns the length of passwords to generate.
+func (p Policy) length() int {
+	if p.MaxLength > 0 {
+		return p.MaxLength
+	}
+	return p.MinLength
+}
+
+// classMinimum is the minimum count of characters of a class.
+type classMinimum struct {
+	class   characterClass
+	minimum int
+}
+
+// minimums returns the minimum count required of each character class.
+func (p Policy) minimums() []classMinimum {
+	return []classMinimum{
+		{classLower, p.MinLower},
+		{classUpper, p.MinUpper},
+		{classDigit, p.MinDigit},
+		{classSymbol, p.MinSymbol},
+	}
+}
+
+// Validate checks that passwords can be generated with the policy.
+func (p Policy) Validate() error {
+	if p.MinLength <= 0 {
+		return fmt.Errorf("minimum length must be positive")
+	}
+	if p.MaxLength != 0 && p.MaxLength < p.MinLength {
+		return fmt.Errorf("maximum length must not be less than minimum length")
+	}
+
+	charset := p.EffectiveCharset()
+	if len(charset) == 0 {
+		return fmt.Errorf("charset cannot be empty")
+	}
+
+	required := 0
+	for _, m := range p.minimums() {
+		if m.minimum < 0 {
+			return fmt.Errorf("minimum number of %s characters cannot be negative", m.class.name)
+		}
+		if m.minimum > 0 && m.class.filter(charset) == "" {
+			return fmt.Errorf("charset has no %s characters", m.class.name)
+		}
+		required += m.minimum
+	}
+	if required > p.length() {
+		return fmt.Errorf("the minimum numbers of characters exceed the password length")
+	}
+
+	return nil
+}
+
+// Check checks that the password complies with the policy, and returns an error describing the first violated rule.
+func (p Policy) Check(password string) error {
+	length := len([]rune(password))
+	if length < p.MinLength {
+		return fmt.Errorf("shorter than %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("longer than %d characters", p.MaxLength)
+	}
+
+	charset := p.EffectiveCharset()
+	for _, r := range password {
+		if !strings.ContainsRune(charset, r) {
+			return fmt.Errorf("contains character not in the charset")
+		}
+	}
+
+	for _, m := range p.minimums() {
+		if count := len([]rune(m.class.filter(password))); count < m.minimum {
+			return fmt.Errorf("fewer than %d %s characters", m.minimum, m.class.name)
+		}
+	}
+
+	return nil
+}
+
+// AuditPolicy checks the password of each entry with a stored generation policy against it,
+// and returns the names of the entries violating their policy mapped to a description of the violated rule.
+func AuditPolicy(filename string) (map[string]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make(map[string]string)
+	for _, entry := range data {
+		if entry.GenPolicy == nil {
+			continue
+		}
+		if err := entry.GenPolicy.Check(entry.Password); err != nil {
+			violations[entry.Name] = err.Error()
+		}
+	}
+	return violations, nil
+}
+
+// GenerateWithPolicy generates a random password complying with the policy.
+func GenerateWithPolicy(p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	charset := p.EffectiveCharset()
+	length := p.length()
+	password := make([]rune, 0, length)
+	for _, m := range p.minimums() {
+		classChars := []rune(m.class.filter(charset))
+		for i := 0; i < m.minimum; i++ {
+			c, err := randomElement(classChars)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+	}
+
+	chars := []rune(charset)
+	for len(password) < length {
+		c, err := randomElement(chars)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle, so that the required characters are not at predictable positions
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomElement(elements []rune) (rune, error) {
+	idx, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(elements))))
+	if err != nil {
+		return 0, err
+	}
+	return elements[idx.Int64()], nil
+}
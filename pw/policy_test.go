@@ -0,0 +1,75 @@
+package pw
+
+import (
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		valid  bool
+	}{
+		{Policy{MinLength: 12, Charset: "abc123"}, true},
+		{Policy{MinLength: 12, MaxLength: 16, Charset: "abc123", MinDigit: 2, MinLower: 2}, true},
+		{Policy{MinLength: 0, Charset: "abc"}, false},
+		{Policy{MinLength: 12, MaxLength: 8, Charset: "abc"}, false},
+		{Policy{MinLength: 12, Charset: "abc", Exclude: "abc"}, false},
+		{Policy{MinLength: 12, Charset: "abc", MinDigit: 1}, false},
+		{Policy{MinLength: 12, Charset: "abc", MinLower: -1}, false},
+		{Policy{MinLength: 4, Charset: "aA1!", MinLower: 2, MinUpper: 2, MinDigit: 1}, false},
+	}
+	for _, test := range tests {
+		if err := test.policy.Validate(); (err == nil) != test.valid {
+			t.Errorf("validating %+v gave %v, expected valid %v", test.policy, err, test.valid)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 10, Charset: "abcdefABCDEF0123456789!?", Exclude: "0", MinUpper: 1, MinDigit: 2, MinSymbol: 1}
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"abcA12!d", true},
+		{"abcA12!", false},
+		{"abcA12!defab", false},
+		{"abcA10!d", false},
+		{"abcz12!d", false},
+		{"abca12!d", false},
+		{"abcA1b!d", false},
+		{"abcA12bd", false},
+	}
+	for _, test := range tests {
+		if err := policy.Check(test.password); (err == nil) != test.valid {
+			t.Errorf("checking %s gave %v, expected valid %v", test.password, err, test.valid)
+		}
+	}
+}
+
+func TestGenerateWithPolicy(t *testing.T) {
+	policies := []Policy{
+		{MinLength: 12, Charset: "abcdefABCDEF0123456789!?"},
+		{MinLength: 8, MaxLength: 20, Charset: "abcdefABCDEF0123456789!?", MinUpper: 3, MinDigit: 3, MinSymbol: 3},
+		{MinLength: 4, Charset: "aA1!", MinLower: 1, MinUpper: 1, MinDigit: 1, MinSymbol: 1},
+		{MinLength: 16, Charset: "abcdefghij0123456789", Exclude: "0123456", MinDigit: 8},
+	}
+	for _, policy := range policies {
+		for i := 0; i < 20; i++ {
+			password, err := GenerateWithPolicy(policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(password) != policy.length() {
+				t.Errorf("generated %s with %+v, expected length %d", password, policy, policy.length())
+			}
+			if err := policy.Check(password); err != nil {
+				t.Errorf("generated %s with %+v, which does not comply: %v", password, policy, err)
+			}
+		}
+	}
+
+	if _, err := GenerateWithPolicy(Policy{MinLength: 8, Charset: "abc", MinDigit: 1}); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+}
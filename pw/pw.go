@@ -4,14 +4,20 @@
 package pw
 
 import (
+	"bytes"
+	"compress/gzip"
 	cryptorand "crypto/rand"
-	"encoding/json"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
 	"math/big"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
 )
 
 var (
@@ -19,13 +25,66 @@ var (
 	ErrPwFileAlreadyExists = errors.New("password file already exists")
 	ErrPwNotFound          = errors.New("password not found")
 	ErrPwAlreadyExists     = errors.New("password already exists")
+	ErrAliasNotFound       = errors.New("alias not found")
+	ErrAliasAlreadyExists  = errors.New("alias already exists")
+	ErrTagNotFound         = errors.New("tag not found")
 )
 
+// Compress makes the data be compressed with gzip before it is encrypted.
+// Compressed data is detected automatically when decrypting, regardless of this setting.
+var Compress bool
+
+// VerifyWrites makes every write decrypt the new file again and check that it has the written content.
+// If it has not, the previous version is restored from a backup.
+var VerifyWrites bool
+
+// FileMode is the permission mode of the password file, applied every time it is written.
+var FileMode os.FileMode = 0600
+
 // PasswordEntry represents an entry in the password file.
 type PasswordEntry struct {
-	Name     string `json:"name"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	// ID is a UUID identifying the entry, which stays the same when it is renamed. It is set when the entry is
+	// added, and older entries may not have one.
+	ID       string    `json:"id,omitempty"`
+	Name     string    `json:"name"`
+	Username string    `json:"username"`
+	Password string    `json:"password"`
+	Aliases  []string  `json:"aliases,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Accounts []Account `json:"accounts,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	// Notes is free text about the entry, like a password hint. It is encrypted with the secrets in a sealed
+	// password file.
+	Notes string `json:"notes,omitempty"`
+	// CreatedAt is when the entry was added, if known.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	// UpdatedAt is when the entry was last changed, if known.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// ExpiresAt is when the entry expires, if set. Expired entries are left out when reading.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Sealed is the individually encrypted secrets of the entry, in a sealed password file.
+	Sealed string `json:"sealed,omitempty"`
+	// GenPolicy is the policy to generate a new password with when rotating it, if set.
+	GenPolicy *Policy `json:"genPolicy,omitempty"`
+	TOTP      *TOTP   `json:"totp,omitempty"`
+	// KeyType is the type of the private key, if the entry holds one.
+	KeyType    string `json:"keyType,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	// RecoveryPhrase is a recovery phrase for the account, like a BIP-39 mnemonic, if set.
+	RecoveryPhrase string `json:"recoveryPhrase,omitempty"`
+}
+
+// hasName reports whether the entry is named name, either directly or by an alias.
+func (e PasswordEntry) hasName(name string) bool {
+	if e.Name == name {
+		return true
+	}
+	for _, alias := range e.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Init creates a new empty password file.
@@ -34,18 +93,18 @@ func Init(filename string) error {
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	if _, err := os.Stat(filename); err == nil {
+	if _, err := os.Stat(filename); err == nil || splitExists(filename) {
 		return ErrPwFileAlreadyExists
 	}
 
-	if err := write(filename, nil); err != nil {
+	if err := write(filename, &document{}); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Get fetches a password entry by name.
+// Get fetches a password entry by name or alias.
 func Get(filename string, name string) (*PasswordEntry, error) {
 	if len(filename) == 0 {
 		return nil, fmt.Errorf("filename cannot be empty")
@@ -57,13 +116,50 @@ func Get(filename string, name string) (*PasswordEntry, error) {
 	}
 
 	for _, entry := range data {
-		if entry.Name == name {
+		if entry.hasName(name) {
+			if err := Unseal(&entry); err != nil {
+				return nil, err
+			}
 			return &entry, nil
 		}
 	}
 	return nil, ErrPwNotFound
 }
 
+// GetMany fetches the password entries with the given names or aliases in a single read, in the order of names,
+// and returns them along with the names which are not found.
+func GetMany(filename string, names []string) ([]PasswordEntry, []string, error) {
+	if len(filename) == 0 {
+		return nil, nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []PasswordEntry
+	var missing []string
+	found := make(map[string]bool, len(names))
+	for _, name := range names {
+		i := entryIndex(data, name)
+		if i < 0 {
+			missing = append(missing, name)
+			continue
+		}
+		if found[data[i].Name] {
+			continue
+		}
+		found[data[i].Name] = true
+		entry := data[i]
+		if err := Unseal(&entry); err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, missing, nil
+}
+
 // List fetches all password entries.
 func List(filename string) ([]PasswordEntry, error) {
 	if len(filename) == 0 {
@@ -79,19 +175,33 @@ func Add(filename string, newEntry PasswordEntry) error {
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	data, err := read(filename)
+	doc, err := readDocument(filename)
 	if err != nil {
 		return err
 	}
 
-	for _, entry := range data {
-		if entry.Name == newEntry.Name {
+	for _, entry := range doc.Entries {
+		if entry.hasName(newEntry.Name) {
 			return ErrPwAlreadyExists
 		}
 	}
 
-	data = append(data, newEntry)
-	return write(filename, data)
+	return addEntry(filename, doc, newEntry)
+}
+
+// addEntry adds a new password entry to the document read from the password file, and writes it.
+func addEntry(filename string, doc *document, newEntry PasswordEntry) error {
+	var err error
+	if newEntry.ID == "" {
+		newEntry.ID, err = newID()
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now().UTC()
+	newEntry.CreatedAt = &now
+	newEntry.UpdatedAt = &now
+	return write(filename, doc.with(append(doc.Entries, newEntry)))
 }
 
 // Update updates an existing password entry.
@@ -100,14 +210,21 @@ func Update(filename string, newEntry PasswordEntry) error {
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	data, err := read(filename)
+	doc, err := readDocument(filename)
 	if err != nil {
 		return err
 	}
+	data := doc.Entries
 
 	found := false
 	for i, entry := range data {
 		if entry.Name == newEntry.Name {
+			now := time.Now().UTC()
+			if newEntry.CreatedAt == nil {
+				newEntry.CreatedAt = entry.CreatedAt
+			}
+			newEntry.ID = entry.ID
+			newEntry.UpdatedAt = &now
 			data[i] = newEntry
 			found = true
 			break
@@ -118,24 +235,107 @@ func Update(filename string, newEntry PasswordEntry) error {
 		return ErrPwNotFound
 	}
 
-	return write(filename, data)
+	return write(filename, doc.with(data))
+}
+
+// Modify modifies an existing password entry in place with fn, and writes the file unless fn returns an error.
+func Modify(filename string, name string, fn func(entry *PasswordEntry) error) error {
+	return modifyWhere(filename, func(entry PasswordEntry) bool {
+		return entry.Name == name
+	}, fn)
+}
+
+// modifyWhere modifies the first password entry matching pred in place with fn, like Modify.
+func modifyWhere(filename string, pred func(entry PasswordEntry) bool, fn func(entry *PasswordEntry) error) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	data := doc.Entries
+
+	for i, entry := range data {
+		if pred(entry) {
+			if err := Unseal(&data[i]); err != nil {
+				return err
+			}
+			if err := fn(&data[i]); err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			data[i].UpdatedAt = &now
+			return write(filename, doc.with(data))
+		}
+	}
+
+	return ErrPwNotFound
+}
+
+// ChangedSince returns the password entries added or changed after since.
+// Entries without timestamps are not included.
+func ChangedSince(filename string, since time.Time) ([]PasswordEntry, error) {
+	changed, _, err := partitionByUpdate(filename, func(updatedAt time.Time) bool {
+		return updatedAt.After(since)
+	})
+	return changed, err
+}
+
+// OlderThan returns the password entries last added or changed before before, and separately the entries
+// without timestamps, whose age is unknown.
+func OlderThan(filename string, before time.Time) (older []PasswordEntry, unknown []PasswordEntry, err error) {
+	return partitionByUpdate(filename, func(updatedAt time.Time) bool {
+		return updatedAt.Before(before)
+	})
+}
+
+// partitionByUpdate returns the password entries whose update time matches pred, and separately the entries
+// without timestamps.
+func partitionByUpdate(filename string, pred func(updatedAt time.Time) bool) (matching []PasswordEntry, unknown []PasswordEntry, err error) {
+	if len(filename) == 0 {
+		return nil, nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range data {
+		if entry.UpdatedAt == nil {
+			unknown = append(unknown, entry)
+		} else if pred(*entry.UpdatedAt) {
+			matching = append(matching, entry)
+		}
+	}
+	return matching, unknown, nil
 }
 
 // Remove removes a password entry.
 func Remove(filename string, name string) error {
+	return removeWhere(filename, func(entry PasswordEntry) bool {
+		return entry.Name == name
+	})
+}
+
+// removeWhere removes the password entries matching pred, and returns ErrPwNotFound if there are none.
+func removeWhere(filename string, pred func(entry PasswordEntry) bool) error {
 	if len(filename) == 0 {
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	data, err := read(filename)
+	doc, err := readDocument(filename)
 	if err != nil {
 		return err
 	}
+	data := doc.Entries
 
 	newData := make([]PasswordEntry, 0, len(data))
 	found := false
 	for _, entry := range data {
-		if entry.Name != name {
+		if !pred(entry) {
 			newData = append(newData, entry)
 		} else {
 			found = true
@@ -146,10 +346,367 @@ func Remove(filename string, name string) error {
 		return ErrPwNotFound
 	}
 
-	return write(filename, newData)
+	return write(filename, doc.with(newData))
+}
+
+// RemoveMany removes the password entries with the given names in a single write,
+// and returns the names which were not found.
+func RemoveMany(filename string, names []string) ([]string, error) {
+	return removeMany(filename, names, false)
+}
+
+// RemoveManyStrict removes the password entries with the given names in a single write.
+// If any of the names is not found, nothing is removed, and ErrPwNotFound is returned along with the missing names.
+func RemoveManyStrict(filename string, names []string) ([]string, error) {
+	return removeMany(filename, names, true)
+}
+
+func removeMany(filename string, names []string, strict bool) ([]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return nil, err
+	}
+	data := doc.Entries
+
+	toRemove := make(map[string]bool, len(names))
+	for _, name := range names {
+		toRemove[name] = true
+	}
+
+	newData := make([]PasswordEntry, 0, len(data))
+	found := make(map[string]bool, len(names))
+	for _, entry := range data {
+		if toRemove[entry.Name] {
+			found[entry.Name] = true
+		} else {
+			newData = append(newData, entry)
+		}
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if strict && len(missing) > 0 {
+		return missing, ErrPwNotFound
+	}
+
+	if len(found) == 0 {
+		return missing, nil
+	}
+
+	return missing, write(filename, doc.with(newData))
+}
+
+// VerifyPassword reports whether candidate matches the password of the entry with the given name or alias.
+// The comparison is done in constant time.
+func VerifyPassword(filename string, name string, candidate string) (bool, error) {
+	entry, err := Get(filename, name)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(entry.Password), []byte(candidate)) == 1, nil
+}
+
+// AddAlias adds an alias to the password entry with the given name.
+// The alias must not be used as a name or alias by any entry.
+func AddAlias(filename string, name string, alias string) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	if len(alias) == 0 {
+		return fmt.Errorf("alias cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	data := doc.Entries
+
+	index := -1
+	for i, entry := range data {
+		if entry.hasName(alias) {
+			return ErrAliasAlreadyExists
+		}
+		if entry.Name == name {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		return ErrPwNotFound
+	}
+
+	data[index].Aliases = append(data[index].Aliases, alias)
+	return write(filename, doc.with(data))
+}
+
+// RemoveAlias removes an alias from the password entry with the given name.
+func RemoveAlias(filename string, name string, alias string) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	data := doc.Entries
+
+	for i, entry := range data {
+		if entry.Name == name {
+			newAliases := make([]string, 0, len(entry.Aliases))
+			found := false
+			for _, a := range entry.Aliases {
+				if a != alias {
+					newAliases = append(newAliases, a)
+				} else {
+					found = true
+				}
+			}
+
+			if !found {
+				return ErrAliasNotFound
+			}
+
+			data[i].Aliases = newAliases
+			return write(filename, doc.with(data))
+		}
+	}
+
+	return ErrPwNotFound
+}
+
+// HasTag reports whether the entry has the tag.
+func (e PasswordEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetURL sets the URL of the password entry with the given name, or removes it if url is empty.
+func SetURL(filename string, name string, url string) error {
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		entry.URL = url
+		return nil
+	})
+}
+
+// AddTag adds a tag to the password entry with the given name, unless it already has it.
+func AddTag(filename string, name string, tag string) error {
+	if len(tag) == 0 {
+		return fmt.Errorf("tag cannot be empty")
+	}
+
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		if !entry.HasTag(tag) {
+			entry.Tags = append(entry.Tags, tag)
+		}
+		return nil
+	})
+}
+
+// TagWhere adds a tag to every password entry matching pred, unless it already has it, in a single write.
+// It returns the number of entries tagged.
+func TagWhere(filename string, pred func(PasswordEntry) bool, tag string) (int, error) {
+	if len(filename) == 0 {
+		return 0, fmt.Errorf("filename cannot be empty")
+	}
+	if len(tag) == 0 {
+		return 0, fmt.Errorf("tag cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return 0, err
+	}
+	data := doc.Entries
+
+	tagged := 0
+	now := time.Now().UTC()
+	for i, entry := range data {
+		if pred(entry) && !entry.HasTag(tag) {
+			data[i].Tags = append(data[i].Tags, tag)
+			data[i].UpdatedAt = &now
+			tagged++
+		}
+	}
+
+	if tagged == 0 {
+		return 0, nil
+	}
+	return tagged, write(filename, doc.with(data))
+}
+
+// RemoveTag removes a tag from the password entry with the given name.
+func RemoveTag(filename string, name string, tag string) error {
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		newTags := make([]string, 0, len(entry.Tags))
+		for _, t := range entry.Tags {
+			if t != tag {
+				newTags = append(newTags, t)
+			}
+		}
+		if len(newTags) == len(entry.Tags) {
+			return ErrTagNotFound
+		}
+		entry.Tags = newTags
+		return nil
+	})
+}
+
+// TagCounts returns the number of password entries with each tag.
+func TagCounts(filename string) (map[string]int, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, entry := range data {
+		seen := make(map[string]bool, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				counts[tag]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// Diff compares the password files a and b by entry name and password.
+// It returns the names of entries only in b (added), only in a (removed),
+// and in both but with different passwords (changed), each sorted by name.
+func Diff(a string, b string) (added []string, removed []string, changed []string, err error) {
+	if len(a) == 0 || len(b) == 0 {
+		return nil, nil, nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	dataA, err := readUnsealed(a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dataB, err := readUnsealed(b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	added, removed, changed = diffEntries(dataA, dataB, func(a PasswordEntry, b PasswordEntry) bool {
+		return a.Password != b.Password
+	})
+	return added, removed, changed, nil
 }
 
+// diffEntries compares the entries dataA and dataB by name, and returns the names of entries only in dataB (added),
+// only in dataA (removed), and in both but differing according to differ (changed), each sorted by name.
+func diffEntries(dataA []PasswordEntry, dataB []PasswordEntry, differ func(a PasswordEntry, b PasswordEntry) bool) (added []string, removed []string, changed []string) {
+	entriesA := make(map[string]PasswordEntry, len(dataA))
+	for _, entry := range dataA {
+		entriesA[entry.Name] = entry
+	}
+	entriesB := make(map[string]PasswordEntry, len(dataB))
+	for _, entry := range dataB {
+		entriesB[entry.Name] = entry
+	}
+
+	for name, entryB := range entriesB {
+		entryA, found := entriesA[name]
+		if !found {
+			added = append(added, name)
+		} else if differ(entryA, entryB) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range entriesA {
+		if _, found := entriesB[name]; !found {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// read reads the entries of the password file, see readDocument.
 func read(filename string) ([]PasswordEntry, error) {
+	doc, err := readDocument(filename)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Entries, nil
+}
+
+// readDocument reads the password file, falling back to its mirror, with the expired entries left out. Functions
+// changing the password file read it with this, and pass the document on to write, so that the description and
+// seal header are kept.
+func readDocument(filename string) (*document, error) {
+	doc, err := readFile(filename)
+	if err != nil {
+		doc, err = readMirror(filename, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pruneExpired(filename, doc), nil
+}
+
+// readFile reads the password file, without falling back to its mirror.
+func readFile(filename string) (*document, error) {
+	output, err := decrypt(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := unmarshalDocument(output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := registerSealKey(filename, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// decrypt returns the decrypted contents of the file.
+func decrypt(filename string) ([]byte, error) {
+	return decryptWith(DefaultBackend, filename)
+}
+
+// decryptWith returns the contents of the file decrypted with the backend.
+func decryptWith(backend Backend, filename string) ([]byte, error) {
+	if variable, ok := envVariable(filename); ok {
+		return readEnv(variable)
+	}
+	if shares, ok := Splits[filename]; ok {
+		joined, err := joinShares(shares)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = os.Remove(joined) }()
+		filename = joined
+	}
+
 	fileMode, err := os.Stat(filename)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, ErrPwFileNotFound
@@ -160,65 +717,209 @@ func read(filename string) ([]PasswordEntry, error) {
 	if fileMode.IsDir() {
 		return nil, fmt.Errorf("%s is a directory", filename)
 	}
-
-	cmd := exec.Command("scrypt", "dec", filename)
-	cmd.Stderr = os.Stderr
-	output, err := cmd.Output()
-	var exitErr *exec.ExitError
-	if errors.As(err, &exitErr) {
-		return nil, fmt.Errorf("unable to execute scrypt dec: %w\n%s", err, string(exitErr.Stderr))
+	if err := checkPermissions(filename, fileMode); err != nil {
+		return nil, err
+	}
+	if content, err := os.ReadFile(filename); err == nil && isSealed(content) {
+		return content, nil
+	} else if err == nil && isArmored(content) {
+		dearmored, err := dearmorFile(filename, content)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = os.Remove(dearmored) }()
+		filename = dearmored
 	}
+
+	start := time.Now()
+	output, err := backend.Decrypt(filename)
 	if err != nil {
-		return nil, fmt.Errorf("unable to execute scrypt dec: %w", err)
+		return nil, err
 	}
+	slog.Info("decrypted", "file", filename, "backend", backend.Name(), "duration", time.Since(start))
 
-	var data []PasswordEntry
-	if err := json.Unmarshal(output, &data); err != nil {
-		return nil, fmt.Errorf("invalid JSON: %w", err)
+	if bytes.HasPrefix(output, gzipMagic) {
+		output, err = gunzip(output)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compressed data: %w", err)
+		}
 	}
 
-	return data, nil
+	return output, nil
 }
 
-func write(filename string, data []PasswordEntry) error {
-	jsonData, err := json.Marshal(data)
+// write encrypts the document to the file, with the entries sorted by name so that the plaintext is deterministic.
+// If the file is sealed with a new key, the seal header is set in doc.
+func write(filename string, doc *document) error {
+	if variable, ok := envVariable(filename); ok {
+		return fmt.Errorf("%w: %s is read from environment variable %s", ErrReadOnly, filename, variable)
+	}
+
+	sorted := make([]PasswordEntry, len(doc.Entries))
+	copy(sorted, doc.Entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	sealed := Seal || doc.Seal != nil || fileIsSealed(filename)
+	armored := !sealed && (Armor || fileIsArmored(filename))
+	var key *sealKey
+	if sealed {
+		var err error
+		key, err = sealKeyFor(doc)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range sorted {
+		var err error
+		if sealed {
+			err = seal(key, &sorted[i])
+		} else {
+			err = Unseal(&sorted[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var jsonData []byte
+	var err error
+	if sealed {
+		jsonData, err = marshalSealed(sorted, doc, key)
+	} else {
+		var description string
+		description, err = doc.description()
+		if err != nil {
+			return err
+		}
+		jsonData, err = marshalEntries(sorted, description)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to marshal to JSON: %w", err)
 	}
 
-	cmd := exec.Command("scrypt", "enc", "-", filename)
-	cmd.Stderr = os.Stderr
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
+	if err := runHook(PreWriteHook, filename); err != nil {
 		return err
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("unable to execute scrypt enc: %w", err)
+	if err := encrypt(filename, jsonData, sealed, armored); err != nil {
+		return err
 	}
 
-	if _, err := stdin.Write(jsonData); err != nil {
-		_ = cmd.Cancel()
-		return err
+	if err := updateMirror(filename); err != nil {
+		slog.Warn("mirror not updated", "file", filename, "error", err)
 	}
-	_ = stdin.Close()
 
-	err = cmd.Wait()
-	var exitErr *exec.ExitError
-	if errors.As(err, &exitErr) {
-		return fmt.Errorf("unable to wait for scrypt enc: %w\n%s", err, string(exitErr.Stderr))
+	if err := runHook(PostWriteHook, filename); err != nil {
+		slog.Warn("post-write hook failed", "file", filename, "error", err)
 	}
+
+	return nil
+}
+
+// encrypt encrypts data to the file, armored if armored is set, or just writes it if the file is sealed. The data
+// is first written to a temporary file, which then replaces the file, so that the file is never left partially
+// written.
+func encrypt(filename string, data []byte, sealed bool, armored bool) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
 	if err != nil {
-		return fmt.Errorf("unable to wait for scrypt enc: %w", err)
+		return fmt.Errorf("unable to create temporary file: %w", err)
 	}
+	tmpFilename := tmpFile.Name()
+	_ = tmpFile.Close()
+	defer func() { _ = os.Remove(tmpFilename) }()
+
+	plaintext := data
+	if sealed {
+		if err := os.WriteFile(tmpFilename, data, FileMode); err != nil {
+			return fmt.Errorf("unable to write temporary file: %w", err)
+		}
+	} else {
+		if Compress {
+			data, err = gzipData(data)
+			if err != nil {
+				return fmt.Errorf("unable to compress: %w", err)
+			}
+		}
 
-	if err := os.Chmod(filename, 0600); err != nil {
+		start := time.Now()
+		if err := DefaultBackend.Encrypt(tmpFilename, data); err != nil {
+			return err
+		}
+		slog.Info("encrypted", "file", tmpFilename, "backend", DefaultBackend.Name(), "duration", time.Since(start))
+
+		if armored {
+			if err := armorFile(tmpFilename); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Chmod(tmpFilename, FileMode); err != nil {
 		return fmt.Errorf("unable to set filename permissions: %w", err)
 	}
 
+	// Do not replace the file if the command has timed out while encrypting
+	if err := Context.Err(); err != nil {
+		return err
+	}
+
+	if shares, ok := Splits[filename]; ok {
+		if err := writeShares(tmpFilename, shares); err != nil {
+			return err
+		}
+		if VerifyWrites {
+			return verifyWrite(filename, plaintext, "")
+		}
+		return nil
+	}
+
+	var backupName string
+	if Backup || VerifyWrites {
+		backupName, err = backup(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		return fmt.Errorf("unable to replace %s: %w", filename, err)
+	}
+	slog.Debug("replaced", "file", filename, "from", tmpFilename)
+
+	if VerifyWrites {
+		if err := verifyWrite(filename, plaintext, backupName); err != nil {
+			return err
+		}
+		if !Backup && backupName != "" {
+			_ = os.Remove(backupName)
+		}
+	}
+
 	return nil
 }
 
+// verifyWrite decrypts the file and checks that it has the expected plaintext.
+// If it has not, the file is restored from the backup, if any.
+func verifyWrite(filename string, expected []byte, backupName string) error {
+	actual, err := decrypt(filename)
+	if err == nil && !bytes.Equal(actual, expected) {
+		err = fmt.Errorf("content differs from what was written")
+	}
+	if err == nil {
+		return nil
+	}
+
+	if backupName == "" {
+		return fmt.Errorf("verification of %s failed: %w", filename, err)
+	}
+	if restoreErr := os.Rename(backupName, filename); restoreErr != nil {
+		return fmt.Errorf("verification of %s failed: %w, and unable to restore backup: %v", filename, err, restoreErr)
+	}
+	return fmt.Errorf("verification of %s failed, previous version restored: %w", filename, err)
+}
+
 // GeneratePassword generates a random password of length characters from the charset.
 func GeneratePassword(length int, charset string) (string, error) {
 	if length <= 0 {
@@ -242,3 +943,27 @@ func GeneratePassword(length int, charset string) (string, error) {
 
 	return string(password), nil
 }
+
+// gzipMagic is the first bytes of gzip compressed data, which can never be the start of JSON text.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func gzipData(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
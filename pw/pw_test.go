@@ -0,0 +1,93 @@
+package pw
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testMagic is the first bytes of files encrypted by testBackend.
+var testMagic = []byte("test")
+
+// testBackend is a backend for tests, which encrypts by XORing the data instead of running scrypt, and fails to
+// decrypt files it has not encrypted.
+type testBackend struct{}
+
+func (testBackend) Name() string {
+	return "test"
+}
+
+func (testBackend) Decrypt(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(content, testMagic) {
+		return nil, errors.New("not encrypted by the test backend")
+	}
+	return xorTest(content[len(testMagic):]), nil
+}
+
+func (testBackend) Encrypt(filename string, data []byte) error {
+	return os.WriteFile(filename, append(bytes.Clone(testMagic), xorTest(data)...), FileMode)
+}
+
+// xorTest returns a copy of data with every byte XORed, so that the plaintext is not visible in encrypted files.
+func xorTest(data []byte) []byte {
+	xored := make([]byte, len(data))
+	for i, b := range data {
+		xored[i] = b ^ 0x5a
+	}
+	return xored
+}
+
+// newTestFile returns the name of a password file in a temporary directory, not yet created, using testBackend.
+// The global settings changed by tests are restored afterward.
+func newTestFile(t *testing.T) string {
+	t.Helper()
+	defaultBackend, seal, armor, compress, backup := DefaultBackend, Seal, Armor, Compress, Backup
+	t.Cleanup(func() {
+		DefaultBackend, Seal, Armor, Compress, Backup = defaultBackend, seal, armor, compress, backup
+		sealKeys = map[string]*sealKey{}
+	})
+	DefaultBackend = testBackend{}
+	Backup = false
+
+	return filepath.Join(t.TempDir(), "passwords")
+}
+
+func TestAddGetRoundTrip(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Username: "alice", Password: "secret", Aliases: []string{"ex"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(content, []byte("secret")) {
+		t.Error("password in plaintext in the file")
+	}
+
+	for _, name := range []string{"example", "ex"} {
+		entry, err := Get(filename, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name != "example" || entry.Username != "alice" || entry.Password != "secret" || entry.ID == "" {
+			t.Errorf("got %+v by %s", entry, name)
+		}
+	}
+	if _, err := Get(filename, "other"); !errors.Is(err, ErrPwNotFound) {
+		t.Errorf("expected ErrPwNotFound, got %v", err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "ex", Password: "other"}); !errors.Is(err, ErrPwAlreadyExists) {
+		t.Errorf("expected ErrPwAlreadyExists adding an alias as name, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateBytes generates n random bytes and encodes them with encoding, base64 or hex, for use as an API key
+// or similar. Unlike GeneratePassword, no charset is used.
+func GenerateBytes(n int, encoding string) (string, error) {
+	if n <= 0 {
+		return "", fmt.Errorf("number of bytes must be positive")
+	}
+
+	var encode func([]byte) string
+	switch encoding {
+	case "base64":
+		encode = base64.StdEncoding.EncodeToString
+	case "hex":
+		encode = hex.EncodeToString
+	default:
+		return "", fmt.Errorf("unknown encoding: %s", encoding)
+	}
+
+	b := make([]byte, n)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate random bytes: %w", err)
+	}
+	return encode(b), nil
+}
@@ -0,0 +1,119 @@
+package pw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RecentFile is a file tracking which entries are used, for MostUsed, if set. It holds the names of used entries,
+// but no passwords, in plaintext.
+var RecentFile string
+
+// use is how often and recently an entry has been used.
+type use struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// frecency returns a score for the use, combining how often and how recently the entry has been used.
+func (u use) frecency(now time.Time) float64 {
+	age := now.Sub(u.LastUsed)
+	weight := 0.25
+	switch {
+	case age < 24*time.Hour:
+		weight = 4
+	case age < 7*24*time.Hour:
+		weight = 2
+	case age < 30*24*time.Hour:
+		weight = 1
+	}
+	return float64(u.Count) * weight
+}
+
+// readRecent reads RecentFile, which maps absolute password filenames to entry names to uses.
+func readRecent() (map[string]map[string]use, error) {
+	recent := map[string]map[string]use{}
+	content, err := os.ReadFile(RecentFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return recent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &recent); err != nil {
+		return nil, fmt.Errorf("invalid recently used file %s: %w", RecentFile, err)
+	}
+	return recent, nil
+}
+
+// RecordUse records that the entry with the given name in the password file has been used, if RecentFile is set.
+func RecordUse(filename string, name string) error {
+	if RecentFile == "" {
+		return nil
+	}
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	recent, err := readRecent()
+	if err != nil {
+		return err
+	}
+	if recent[absFilename] == nil {
+		recent[absFilename] = map[string]use{}
+	}
+	u := recent[absFilename][name]
+	u.Count++
+	u.LastUsed = time.Now().UTC()
+	recent[absFilename][name] = u
+
+	content, err := json.Marshal(recent)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(RecentFile), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(RecentFile, content, 0600)
+}
+
+// MostUsed returns the names of the used entries in the password file, as recorded by RecordUse, most used first
+// by frecency, combining how often and how recently they have been used.
+func MostUsed(filename string) ([]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	if RecentFile == "" {
+		return nil, nil
+	}
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	recent, err := readRecent()
+	if err != nil {
+		return nil, err
+	}
+	uses := recent[absFilename]
+	names := make([]string, 0, len(uses))
+	for name := range uses {
+		names = append(names, name)
+	}
+	now := time.Now()
+	sort.Slice(names, func(i, j int) bool {
+		a, b := uses[names[i]], uses[names[j]]
+		if a.frecency(now) != b.frecency(now) {
+			return a.frecency(now) > b.frecency(now)
+		}
+		return a.LastUsed.After(b.LastUsed)
+	})
+	return names, nil
+}
@@ -0,0 +1,105 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Recover is a last resort for a password file with corrupted JSON, e.g. due to truncation.
+// It salvages every entry that can still be parsed and writes them to the new file newFilename,
+// which must not exist. It returns the number of entries recovered and an estimate of the number lost.
+func Recover(filename string, newFilename string) (recovered int, lost int, err error) {
+	if len(filename) == 0 || len(newFilename) == 0 {
+		return 0, 0, fmt.Errorf("filename cannot be empty")
+	}
+
+	if _, err := os.Stat(newFilename); err == nil {
+		return 0, 0, ErrPwFileAlreadyExists
+	}
+
+	output, err := decrypt(filename)
+	if err != nil {
+		// A corrupted sealed password file is not recognized as sealed, but can still be salvaged
+		content, readErr := os.ReadFile(filename)
+		if readErr != nil || !isPlaintext(content) {
+			return 0, 0, err
+		}
+		output = content
+	}
+
+	doc, lost := salvage(output)
+	if doc.Seal != nil {
+		recoverSealKey(doc.Seal)
+	}
+	if err := write(newFilename, doc); err != nil {
+		return 0, 0, err
+	}
+
+	return len(doc.Entries), lost, nil
+}
+
+// salvage decodes the entries of the content of a password file one by one, and returns a document with those
+// which can be parsed, and the number of those which cannot. Everything after corrupted JSON is lost, and counted
+// as one entry.
+func salvage(content []byte) (*document, int) {
+	doc := &document{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if !findEntries(dec, doc) {
+		return doc, 0
+	}
+
+	lost := 0
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return doc, lost + 1
+		}
+		var entry PasswordEntry
+		if err := json.Unmarshal(raw, &entry); err != nil || entry.Name == "" {
+			lost++
+			continue
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc, lost
+}
+
+// findEntries advances the decoder to the first entry of the content of a password file, in any version, and
+// decodes the description and seal header before it into doc. It reports whether the entries are found.
+func findEntries(dec *json.Decoder, doc *document) bool {
+	token, err := dec.Token()
+	if err != nil {
+		return false
+	}
+	if token == json.Delim('[') {
+		return true
+	}
+	if token != json.Delim('{') {
+		return false
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "entries":
+			token, err := dec.Token()
+			return err == nil && token == json.Delim('[')
+		case "description":
+			err = dec.Decode(&doc.Description)
+		case "seal":
+			err = dec.Decode(&doc.Seal)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return false
+}
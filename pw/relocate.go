@@ -0,0 +1,41 @@
+package pw
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// Relocate moves the password file old to the path new, by decrypting it and encrypting it to new,
+// and then checks that new can be decrypted to the same content. An existing file at new is replaced,
+// and kept as a backup if Backup is set. If removeOld is true, old is removed afterward.
+func Relocate(old string, new string, removeOld bool) error {
+	if len(old) == 0 || len(new) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := decrypt(old)
+	if err != nil {
+		return err
+	}
+
+	sealed := isSealed(data)
+	if err := encrypt(new, data, sealed, !sealed && (Armor || fileIsArmored(old))); err != nil {
+		return err
+	}
+
+	relocated, err := decrypt(new)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt %s: %w", new, err)
+	}
+	if !bytes.Equal(relocated, data) {
+		return fmt.Errorf("content of %s differs from %s", new, old)
+	}
+
+	if removeOld {
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("unable to remove %s: %w", old, err)
+		}
+	}
+	return nil
+}
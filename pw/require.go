@@ -0,0 +1,76 @@
+package pw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRequireAttempts is the number of passwords GenerateRequiring generates before placing the required
+// characters explicitly.
+const maxRequireAttempts = 100
+
+// classByName returns the character class with the given name: lower, upper, digit or symbol.
+func classByName(name string) (characterClass, error) {
+	for _, class := range []characterClass{classLower, classUpper, classDigit, classSymbol} {
+		if class.name == name {
+			return class, nil
+		}
+	}
+	return characterClass{}, fmt.Errorf("unknown character class: %s", name)
+}
+
+// GenerateRequiring generates a random password which contains at least one character of each of the required
+// character classes, given as a comma separated list of lower, upper, digit and symbol.
+// Passwords are generated until one contains all required classes, and if none does after a number of attempts,
+// one character of each required class is placed at a random position instead.
+func GenerateRequiring(length int, charset string, required string) (string, error) {
+	var classes []characterClass
+	for _, name := range strings.Split(required, ",") {
+		class, err := classByName(strings.TrimSpace(name))
+		if err != nil {
+			return "", err
+		}
+		if class.filter(charset) == "" {
+			return "", fmt.Errorf("charset has no %s characters", class.name)
+		}
+		classes = append(classes, class)
+	}
+	if len(classes) > length {
+		return "", fmt.Errorf("the required character classes exceed the password length")
+	}
+
+	for i := 0; i < maxRequireAttempts; i++ {
+		password, err := GeneratePassword(length, charset)
+		if err != nil {
+			return "", err
+		}
+		if containsClasses(password, classes) {
+			return password, nil
+		}
+	}
+
+	policy := Policy{MinLength: length, Charset: charset}
+	for _, class := range classes {
+		switch class.name {
+		case classLower.name:
+			policy.MinLower = 1
+		case classUpper.name:
+			policy.MinUpper = 1
+		case classDigit.name:
+			policy.MinDigit = 1
+		case classSymbol.name:
+			policy.MinSymbol = 1
+		}
+	}
+	return GenerateWithPolicy(policy)
+}
+
+// containsClasses reports whether the password contains at least one character of each of the classes.
+func containsClasses(password string, classes []characterClass) bool {
+	for _, class := range classes {
+		if class.filter(password) == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package pw
+
+import (
+	"testing"
+)
+
+func TestGenerateRequiring(t *testing.T) {
+	tests := []struct {
+		length   int
+		charset  string
+		required string
+		classes  []characterClass
+	}{
+		{12, "abcdefABCDEF0123456789!?", "lower,upper,digit,symbol", []characterClass{classLower, classUpper, classDigit, classSymbol}},
+		{8, "abcdefABCDEF0123456789!?", "digit", []characterClass{classDigit}},
+		// A single symbol among many letters is rarely generated in a short password, so it is placed explicitly
+		{4, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ!", "symbol", []characterClass{classSymbol}},
+		{3, "aA1", "lower, upper, digit", []characterClass{classLower, classUpper, classDigit}},
+	}
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			password, err := GenerateRequiring(test.length, test.charset, test.required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(password) != test.length {
+				t.Errorf("generated %s, expected length %d", password, test.length)
+			}
+			if !containsClasses(password, test.classes) {
+				t.Errorf("generated %s, which does not contain all of %s", password, test.required)
+			}
+		}
+	}
+
+	errorTests := []struct {
+		length   int
+		charset  string
+		required string
+	}{
+		{12, "abc", "digit"},
+		{12, "abc", "punctuation"},
+		{1, "aA", "lower,upper"},
+	}
+	for _, test := range errorTests {
+		if _, err := GenerateRequiring(test.length, test.charset, test.required); err == nil {
+			t.Errorf("expected an error requiring %s of length %d from %s", test.required, test.length, test.charset)
+		}
+	}
+}
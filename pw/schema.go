@@ -0,0 +1,118 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// schemaVersion is the version of the format of the password file content.
+// Files written before versioning was introduced have a bare JSON array of entries, which is version 0.
+const schemaVersion = 1
+
+// document is the content of a password file.
+type document struct {
+	Version int `json:"version"`
+	// Description is a free-form note about the password file. In a sealed password file, it is sealed in the
+	// seal header instead.
+	Description string `json:"description,omitempty"`
+	// Seal is the seal header of a sealed password file, see Seal.
+	Seal    *sealHeader     `json:"seal,omitempty"`
+	Entries []PasswordEntry `json:"entries"`
+}
+
+// with returns a copy of the document with the entries.
+func (d *document) with(entries []PasswordEntry) *document {
+	doc := *d
+	doc.Entries = entries
+	return &doc
+}
+
+// description returns the description of the document, unsealing it if needed.
+func (d *document) description() (string, error) {
+	return d.descriptionWith(DefaultBackend)
+}
+
+func (d *document) descriptionWith(backend Backend) (string, error) {
+	if d.Description != "" || d.Seal == nil || d.Seal.Description == "" {
+		return d.Description, nil
+	}
+	return unsealDescription(backend, d.Seal)
+}
+
+// unmarshalDocument parses the content of a password file, in any supported version. Old versions are upgraded to
+// the current version when the file is written again.
+func unmarshalDocument(content []byte) (*document, error) {
+	if bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte("[")) {
+		var data []PasswordEntry
+		if err := json.Unmarshal(content, &data); err != nil {
+			return nil, err
+		}
+		return &document{Entries: data}, nil
+	}
+
+	var doc document
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version > schemaVersion {
+		return nil, fmt.Errorf("unsupported version %d, upgrade gopw", doc.Version)
+	}
+	return &doc, nil
+}
+
+// marshalEntries formats the entries and description as the content of a password file in the current version.
+func marshalEntries(data []PasswordEntry, description string) ([]byte, error) {
+	if data == nil {
+		data = []PasswordEntry{}
+	}
+	return json.Marshal(document{Version: schemaVersion, Description: description, Entries: data})
+}
+
+// Description returns the description of the password file.
+func Description(filename string) (string, error) {
+	if len(filename) == 0 {
+		return "", fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return "", err
+	}
+	return doc.description()
+}
+
+// SetDescription sets the description of the password file, or removes it if description is empty.
+func SetDescription(filename string, description string) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	doc.Description = description
+	if doc.Seal != nil {
+		doc.Seal.Description = ""
+	}
+	return write(filename, doc)
+}
+
+// MigrateFormat rewrites the password file in the current version of the format, unless it already is, and
+// returns the version before and after. Files are otherwise upgraded when they are written for other reasons.
+func MigrateFormat(filename string) (from int, to int, err error) {
+	if len(filename) == 0 {
+		return 0, 0, fmt.Errorf("filename cannot be empty")
+	}
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	from = doc.Version
+	if from == schemaVersion {
+		return from, from, nil
+	}
+	return from, schemaVersion, write(filename, doc)
+}
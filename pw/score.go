@@ -0,0 +1,110 @@
+package pw
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Weights of the problems in the health score, as the points deducted if all entries have the problem.
+const (
+	scoreWeightWeak   = 40
+	scoreWeightReused = 30
+	scoreWeightNoTOTP = 15
+	scoreWeightStale  = 15
+)
+
+// scoreMinEntropy is the strength in bits below which a password is weak in the health score.
+const scoreMinEntropy = 60
+
+// scoreStaleDuration is the time after which an entry not changed is stale in the health score.
+const scoreStaleDuration = 365 * 24 * time.Hour
+
+// Deduction is a problem lowering the health score of a password file.
+type Deduction struct {
+	// Reason describes the problem.
+	Reason string
+	// Names are the names of the entries with the problem, sorted.
+	Names []string
+	// Points is the number of points deducted.
+	Points int
+}
+
+// ScoreResult is the health score of a password file.
+type ScoreResult struct {
+	// Score is from 0 to 100, where 100 means no problems were found.
+	Score int
+	// Deductions are the problems found, with the points deducted for each.
+	Deductions []Deduction
+}
+
+// Score calculates a health score from 0 to 100 for the password file, deducting points for weak passwords
+// (estimated below 60 bits by PasswordStrength), passwords reused by other entries (not counting empty passwords),
+// entries without TOTP, and entries not changed in a year. The points deducted for each problem are proportional
+// to the share of entries with it. Entries holding a private key are not counted.
+func Score(filename string) (ScoreResult, error) {
+	if len(filename) == 0 {
+		return ScoreResult{}, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return ScoreResult{}, err
+	}
+
+	var entries []PasswordEntry
+	for _, entry := range data {
+		if entry.KeyType == "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	var weak []string
+	for _, entry := range weakEntries(entries, scoreMinEntropy) {
+		weak = append(weak, entry.Name)
+	}
+
+	// Entries without a password are not reusing one
+	byPassword := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.Password == "" {
+			continue
+		}
+		byPassword[entry.Password] = append(byPassword[entry.Password], entry.Name)
+	}
+	var reused, noTOTP, stale []string
+	staleBefore := time.Now().Add(-scoreStaleDuration)
+	for _, entry := range entries {
+		if len(byPassword[entry.Password]) > 1 {
+			reused = append(reused, entry.Name)
+		}
+		if entry.TOTP == nil {
+			noTOTP = append(noTOTP, entry.Name)
+		}
+		if entry.UpdatedAt != nil && entry.UpdatedAt.Before(staleBefore) {
+			stale = append(stale, entry.Name)
+		}
+	}
+
+	result := ScoreResult{Score: 100}
+	for _, problem := range []struct {
+		reason string
+		names  []string
+		weight int
+	}{
+		{"weak passwords", weak, scoreWeightWeak},
+		{"reused passwords", reused, scoreWeightReused},
+		{"no TOTP", noTOTP, scoreWeightNoTOTP},
+		{"not changed in a year", stale, scoreWeightStale},
+	} {
+		if len(problem.names) == 0 {
+			continue
+		}
+		sort.Strings(problem.names)
+		points := int(math.Round(float64(problem.weight) * float64(len(problem.names)) / float64(len(entries))))
+		result.Deductions = append(result.Deductions, Deduction{problem.reason, problem.names, points})
+		result.Score -= points
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package pw
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	stale := now.Add(-2 * scoreStaleDuration)
+	totp := &TOTP{Secret: "GEZDGNBV"}
+	if err := Transaction(filename, func(entries *[]PasswordEntry) error {
+		*entries = []PasswordEntry{
+			{Name: "strong", Password: "Xk9#mP2$vL7@qR4!wT8&zY", TOTP: totp, UpdatedAt: &now},
+			{Name: "weak", Password: "abc", TOTP: totp, UpdatedAt: &now},
+			{Name: "reused1", Password: "Qw8!eR5@tY2#uI9$oP", TOTP: totp, UpdatedAt: &now},
+			{Name: "reused2", Password: "Qw8!eR5@tY2#uI9$oP", TOTP: totp, UpdatedAt: &now},
+			{Name: "stale", Password: "Zx7&cV4^bN1*mL6%pK3", TOTP: totp, UpdatedAt: &stale},
+			// Empty passwords are weak, but not reused
+			{Name: "empty1", TOTP: totp, UpdatedAt: &now},
+			{Name: "empty2", TOTP: totp, UpdatedAt: &now},
+			{Name: "nototp", Password: "Mn5$bV8@cX2!zL9#kJ4", UpdatedAt: &now},
+			// Entries holding a private key are not counted
+			{Name: "key", KeyType: "ed25519", PrivateKey: "key"},
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Score(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Deduction{
+		{"weak passwords", []string{"empty1", "empty2", "weak"}, 15},
+		{"reused passwords", []string{"reused1", "reused2"}, 8},
+		{"no TOTP", []string{"nototp"}, 2},
+		{"not changed in a year", []string{"stale"}, 2},
+	}
+	if len(result.Deductions) != len(expected) {
+		t.Fatalf("got deductions %+v, expected %+v", result.Deductions, expected)
+	}
+	for i, deduction := range result.Deductions {
+		if deduction.Reason != expected[i].Reason || !slices.Equal(deduction.Names, expected[i].Names) || deduction.Points != expected[i].Points {
+			t.Errorf("got deduction %+v, expected %+v", deduction, expected[i])
+		}
+	}
+	if result.Score != 73 {
+		t.Errorf("got score %d, expected 73", result.Score)
+	}
+}
+
+func TestScoreNoProblems(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "strong", Password: "Xk9#mP2$vL7@qR4!wT8&zY", TOTP: &TOTP{Secret: "GEZDGNBV"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Score(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 100 || len(result.Deductions) != 0 {
+		t.Errorf("got %+v, expected 100 without deductions", result)
+	}
+}
@@ -0,0 +1,108 @@
+//go:build !nativecrypt
+
+package pw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// scryptCommand returns a command running scrypt with args, the first being dec or enc,
+// with the limits ScryptMaxMem and ScryptMaxTime.
+func scryptCommand(args ...string) *exec.Cmd {
+	scryptArgs := []string{args[0]}
+	if ScryptMaxMem > 0 {
+		scryptArgs = append(scryptArgs, "-M", strconv.FormatInt(ScryptMaxMem, 10))
+	}
+	if ScryptMaxTime > 0 {
+		scryptArgs = append(scryptArgs, "-t", strconv.FormatFloat(ScryptMaxTime, 'f', -1, 64))
+	}
+	scryptArgs = append(scryptArgs, args[1:]...)
+	slog.Debug("running scrypt", "args", scryptArgs)
+	return exec.CommandContext(Context, "scrypt", scryptArgs...)
+}
+
+// scryptDecryptOnce decrypts the file with scrypt, returning ErrWrongPassphrase if scrypt exits with status 1
+// and reports the passphrase as incorrect.
+func scryptDecryptOnce(filename string) ([]byte, error) {
+	cmd := scryptCommand("dec", filename)
+	// scrypt prompts on the terminal, so stderr only has errors, which are both shown and checked
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && strings.Contains(stderr.String(), "Passphrase is incorrect") {
+		return nil, fmt.Errorf("unable to execute scrypt dec: %w", ErrWrongPassphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute scrypt dec: %w", err)
+	}
+
+	return output, nil
+}
+
+// scryptEncrypt encrypts data to the file with scrypt, which prompts for the passphrase.
+func scryptEncrypt(filename string, data []byte) error {
+	cmd := scryptCommand("enc", "-", filename)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to execute scrypt enc: %w", err)
+	}
+
+	if _, err := stdin.Write(data); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	_ = stdin.Close()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("unable to wait for scrypt enc: %w\n%s", err, string(exitErr.Stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to wait for scrypt enc: %w", err)
+	}
+
+	return nil
+}
+
+// scryptWithPassphrase runs scrypt dec or enc, given by op, passing the passphrase on file descriptor 3 and input
+// on stdin, and returns its output.
+func scryptWithPassphrase(op string, input []byte, passphrase string) ([]byte, error) {
+	passphraseReader, passphraseWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = passphraseReader.Close() }()
+
+	cmd := scryptCommand(op, "--passphrase", "file:/dev/fd/3", "-")
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.ExtraFiles = []*os.File{passphraseReader}
+
+	go func() {
+		_, _ = fmt.Fprintln(passphraseWriter, passphrase)
+		_ = passphraseWriter.Close()
+	}()
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute scrypt %s: %w\n%s", op, err, stderr.String())
+	}
+	return output, nil
+}
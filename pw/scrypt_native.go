@@ -0,0 +1,221 @@
+//go:build nativecrypt
+
+package pw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// The native scrypt implementation reads and writes the file format of the scrypt command line utility, see
+// https://github.com/Tarsnap/scrypt/blob/master/FORMAT:
+//
+//	offset  length
+//	0       6       "scrypt"
+//	6       1       version, 0
+//	7       1       log2(N)
+//	8       4       r, big-endian
+//	12      4       p, big-endian
+//	16      32      salt
+//	48      16      first 16 bytes of SHA256(bytes 0 .. 47)
+//	64      32      HMAC-SHA256(bytes 0 .. 63)
+//	96      X       data encrypted with AES-256-CTR, starting with a zero counter
+//	96+X    32      HMAC-SHA256(bytes 0 .. 96+X-1)
+//
+// The 64 byte key derived with scrypt from the passphrase and salt holds the AES-256 key in the first 32 bytes, and
+// the HMAC-SHA256 key in the last 32 bytes.
+const (
+	scryptMagic      = "scrypt"
+	scryptHeaderSize = 96
+	scryptMACSize    = 32
+
+	// nativeScryptLogN, nativeScryptR and nativeScryptP are the scrypt parameters for encrypting, using 128 MiB of
+	// memory. log2(N) is lowered to stay within ScryptMaxMem, if set.
+	nativeScryptLogN = 17
+	nativeScryptR    = 8
+	nativeScryptP    = 1
+
+	// nativeScryptMaxMem is the maximum memory in bytes for decrypting if ScryptMaxMem is not set.
+	nativeScryptMaxMem = 2 << 30
+)
+
+// scryptMemory returns the memory in bytes scrypt uses with the parameters.
+func scryptMemory(logN uint8, r uint32) uint64 {
+	return 128 * uint64(r) << logN
+}
+
+// scryptDecryptOnce prompts for the passphrase on the terminal and decrypts the file.
+func scryptDecryptOnce(filename string) ([]byte, error) {
+	ciphertext, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase("Please enter passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return scryptWithPassphrase("dec", ciphertext, passphrase)
+}
+
+// scryptEncrypt prompts for the passphrase twice on the terminal and encrypts data to the file.
+func scryptEncrypt(filename string, data []byte) error {
+	passphrase, err := readPassphrase("Please enter passphrase: ")
+	if err != nil {
+		return err
+	}
+	confirmation, err := readPassphrase("Please confirm passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != confirmation {
+		return fmt.Errorf("passphrases do not match")
+	}
+	ciphertext, err := scryptWithPassphrase("enc", data, passphrase)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, ciphertext, 0600)
+}
+
+// scryptWithPassphrase decrypts or encrypts input, as given by op being dec or enc, with the passphrase.
+func scryptWithPassphrase(op string, input []byte, passphrase string) ([]byte, error) {
+	if err := Context.Err(); err != nil {
+		return nil, err
+	}
+	slog.Debug("running native scrypt", "op", op)
+	switch op {
+	case "dec":
+		return nativeScryptDecrypt(input, []byte(passphrase))
+	case "enc":
+		return nativeScryptEncrypt(input, []byte(passphrase))
+	default:
+		return nil, fmt.Errorf("unknown scrypt operation: %s", op)
+	}
+}
+
+func nativeScryptEncrypt(plaintext []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	logN := uint8(nativeScryptLogN)
+	for ScryptMaxMem > 0 && logN > 1 && scryptMemory(logN, nativeScryptR) > uint64(ScryptMaxMem) {
+		logN--
+	}
+
+	header := make([]byte, scryptHeaderSize, scryptHeaderSize+len(plaintext)+scryptMACSize)
+	copy(header, scryptMagic)
+	header[7] = logN
+	binary.BigEndian.PutUint32(header[8:12], nativeScryptR)
+	binary.BigEndian.PutUint32(header[12:16], nativeScryptP)
+	if _, err := cryptorand.Read(header[16:48]); err != nil {
+		return nil, fmt.Errorf("unable to generate salt: %w", err)
+	}
+	checksum := sha256.Sum256(header[:48])
+	copy(header[48:64], checksum[:16])
+
+	key, err := scrypt.Key(passphrase, header[16:48], 1<<logN, nativeScryptR, nativeScryptP, 64)
+	if err != nil {
+		return nil, err
+	}
+	copy(header[64:96], scryptMAC(key, header[:64]))
+
+	output := header[:scryptHeaderSize+len(plaintext)]
+	stream, err := scryptStream(key)
+	if err != nil {
+		return nil, err
+	}
+	stream.XORKeyStream(output[scryptHeaderSize:], plaintext)
+	return append(output, scryptMAC(key, output)...), nil
+}
+
+func nativeScryptDecrypt(ciphertext []byte, passphrase []byte) ([]byte, error) {
+	if len(ciphertext) < scryptHeaderSize+scryptMACSize || !bytes.HasPrefix(ciphertext, []byte(scryptMagic)) {
+		return nil, fmt.Errorf("not a scrypt encrypted file")
+	}
+	if version := ciphertext[6]; version != 0 {
+		return nil, fmt.Errorf("unsupported scrypt file version: %d", version)
+	}
+	checksum := sha256.Sum256(ciphertext[:48])
+	if !bytes.Equal(checksum[:16], ciphertext[48:64]) {
+		return nil, fmt.Errorf("scrypt file header is corrupt")
+	}
+
+	logN := ciphertext[7]
+	r := binary.BigEndian.Uint32(ciphertext[8:12])
+	p := binary.BigEndian.Uint32(ciphertext[12:16])
+	maxMem := uint64(nativeScryptMaxMem)
+	if ScryptMaxMem > 0 {
+		maxMem = uint64(ScryptMaxMem)
+	}
+	if logN < 1 || logN > 40 || r == 0 || p == 0 || scryptMemory(logN, r) > maxMem {
+		return nil, fmt.Errorf("decrypting would use too much memory, with log2(N) %d and r %d", logN, r)
+	}
+
+	key, err := scrypt.Key(passphrase, ciphertext[16:48], 1<<logN, int(r), int(p), 64)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(scryptMAC(key, ciphertext[:64]), ciphertext[64:96]) {
+		return nil, fmt.Errorf("unable to decrypt with scrypt: %w", ErrWrongPassphrase)
+	}
+	macStart := len(ciphertext) - scryptMACSize
+	if !hmac.Equal(scryptMAC(key, ciphertext[:macStart]), ciphertext[macStart:]) {
+		return nil, fmt.Errorf("scrypt encrypted file is corrupt")
+	}
+
+	stream, err := scryptStream(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, macStart-scryptHeaderSize)
+	stream.XORKeyStream(plaintext, ciphertext[scryptHeaderSize:macStart])
+	return plaintext, nil
+}
+
+// scryptStream returns the AES-256-CTR stream with the first half of the derived key.
+func scryptStream(key []byte) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize)), nil
+}
+
+// scryptMAC returns the HMAC-SHA256 of data with the second half of the derived key.
+func scryptMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key[32:])
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// readPassphrase prompts for a passphrase on the terminal, like the scrypt command line utility.
+func readPassphrase(prompt string) (string, error) {
+	in, out := os.Stdin, os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer func() { _ = tty.Close() }()
+		in, out = tty, tty
+	} else if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("unable to read passphrase: no terminal")
+	}
+
+	_, _ = fmt.Fprint(out, prompt)
+	passphrase, err := term.ReadPassword(int(in.Fd()))
+	_, _ = fmt.Fprintln(out)
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,114 @@
+//go:build nativecrypt
+
+package pw
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/example.scrypt is testdata/example.json encrypted in the file format of the scrypt command line
+// utility, with log2(N) 10, r 8, p 1 and this passphrase. It was made independently of this package, with the
+// scrypt key derivation, AES-256-CTR and HMAC-SHA256 of OpenSSL, following the specification of the format.
+const examplePassphrase = "correct horse battery staple"
+
+func TestNativeScryptDecryptExample(t *testing.T) {
+	ciphertext, err := os.ReadFile(filepath.Join("testdata", "example.scrypt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(filepath.Join("testdata", "example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := nativeScryptDecrypt(ciphertext, []byte(examplePassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, expected) {
+		t.Errorf("decrypted to %q, expected %q", plaintext, expected)
+	}
+
+	if _, err := nativeScryptDecrypt(ciphertext, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase with the wrong passphrase, got %v", err)
+	}
+
+	corrupted := bytes.Clone(ciphertext)
+	corrupted[scryptHeaderSize] ^= 1
+	if _, err := nativeScryptDecrypt(corrupted, []byte(examplePassphrase)); err == nil {
+		t.Error("expected an error for corrupted data")
+	}
+}
+
+func TestNativeScryptRoundTrip(t *testing.T) {
+	ScryptMaxMem = 1 << 20
+	defer func() { ScryptMaxMem = 0 }()
+	plaintext := []byte(`{"version":1,"entries":[]}`)
+
+	ciphertext, err := nativeScryptEncrypt(plaintext, []byte(examplePassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(ciphertext, []byte(scryptMagic)) || len(ciphertext) != scryptHeaderSize+len(plaintext)+scryptMACSize {
+		t.Fatalf("not in the scrypt file format: %x", ciphertext)
+	}
+	decrypted, err := nativeScryptDecrypt(ciphertext, []byte(examplePassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted to %q, expected %q", decrypted, plaintext)
+	}
+}
+
+// TestNativeScryptCommandCompatibility checks that files encrypted natively can be decrypted by the scrypt command
+// line utility, and the other way around, if it is installed.
+func TestNativeScryptCommandCompatibility(t *testing.T) {
+	if _, err := exec.LookPath("scrypt"); err != nil {
+		t.Skip("scrypt command line utility not installed")
+	}
+	t.Setenv("GOPW_TEST_PASSPHRASE", examplePassphrase)
+	ScryptMaxMem = 1 << 20
+	defer func() { ScryptMaxMem = 0 }()
+	plaintext := []byte(`{"version":1,"entries":[]}`)
+	dir := t.TempDir()
+
+	ciphertext, err := nativeScryptEncrypt(plaintext, []byte(examplePassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	native := filepath.Join(dir, "native.scrypt")
+	if err := os.WriteFile(native, ciphertext, 0600); err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := exec.Command("scrypt", "dec", "--passphrase", "env:GOPW_TEST_PASSPHRASE", native).Output()
+	if err != nil {
+		t.Fatalf("scrypt dec: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("scrypt dec decrypted to %q, expected %q", decrypted, plaintext)
+	}
+
+	command := filepath.Join(dir, "command.scrypt")
+	cmd := exec.Command("scrypt", "enc", "--passphrase", "env:GOPW_TEST_PASSPHRASE", "-M", "1048576", "-", command)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("scrypt enc: %v", err)
+	}
+	ciphertext, err = os.ReadFile(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = nativeScryptDecrypt(ciphertext, []byte(examplePassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted to %q, expected %q", decrypted, plaintext)
+	}
+}
@@ -0,0 +1,471 @@
+package pw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Seal makes new password files be written with the secrets of each entry individually encrypted, and everything
+// else in plaintext, so that entries can be listed without decrypting the secrets. The secrets of an entry are then
+// only decrypted when they are needed, e.g. by Get. A password file which is sealed is always written sealed again.
+//
+// The secrets are encrypted with AES-256-GCM with a random key for the file, which is itself encrypted with the
+// backend, so that the passphrase is only needed once. The whole file is authenticated with HMAC-SHA256 with the
+// same key, and checked as soon as the key is decrypted, that is before any secrets are used or the file is written.
+// Listing entries without the passphrase does not check it.
+var Seal bool
+
+var ErrSealedTampered = errors.New("sealed password file has been modified without the passphrase")
+
+// sealFormat is the format of sealed password files, marked in their seal header.
+// Sealed files with a seal header are format 2, older sealed files without one are format 1, and are read but
+// not authenticated, and upgraded when written.
+const sealFormat = 2
+
+// sealedPrefix starts the sealed secrets of an entry in format 2, followed by the ID of the key and the
+// encrypted secrets, separated by colons.
+const sealedPrefix = "v2:"
+
+// sealHeader marks a sealed password file, and holds its key and authentication code.
+type sealHeader struct {
+	Format int `json:"format"`
+	// Key is the key of the file, encrypted with the backend.
+	Key string `json:"key"`
+	// Description is the description of the file, sealed with the key, if set.
+	Description string `json:"description,omitempty"`
+	// MAC is the HMAC-SHA256 of the file content with MAC empty.
+	MAC string `json:"mac"`
+}
+
+// sealKey is the key of a sealed password file.
+type sealKey struct {
+	header sealHeader
+	// key is the decrypted key, the first half for AES-256-GCM and the second half for HMAC-SHA256,
+	// nil until decrypted.
+	key []byte
+	// content is the file content with the MAC empty, to authenticate when the key is decrypted, nil if checked.
+	content []byte
+	// mac is the authentication code of content.
+	mac []byte
+}
+
+// sealKeys are the keys of the sealed password files read, by key ID.
+var sealKeys = map[string]*sealKey{}
+
+// sealedSecrets are the fields of an entry which are encrypted in a sealed password file.
+type sealedSecrets struct {
+	Password       string    `json:"password"`
+	Accounts       []Account `json:"accounts,omitempty"`
+	TOTP           *TOTP     `json:"totp,omitempty"`
+	PrivateKey     string    `json:"privateKey,omitempty"`
+	RecoveryPhrase string    `json:"recoveryPhrase,omitempty"`
+	Notes          string    `json:"notes,omitempty"`
+}
+
+// isSealed reports whether the content of a password file is in the sealed format, which is plaintext JSON with
+// a seal header. Old sealed files without a seal header are only recognized if all entries are sealed, so that
+// a plaintext file is never taken for a sealed one.
+func isSealed(content []byte) bool {
+	if !isPlaintext(content) {
+		return false
+	}
+	doc, err := unmarshalDocument(content)
+	if err != nil {
+		return false
+	}
+	if doc.Seal != nil {
+		return doc.Seal.Format == sealFormat
+	}
+	for _, entry := range doc.Entries {
+		if entry.Sealed == "" || strings.HasPrefix(entry.Sealed, sealedPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlaintext reports whether the content of a password file is plaintext JSON, which it is only if it is sealed.
+func isPlaintext(content []byte) bool {
+	trimmed := bytes.TrimLeft(content, " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{"))
+}
+
+// fileIsSealed reports whether the password file exists and is in the sealed format, also if it is split.
+func fileIsSealed(filename string) bool {
+	content, err := readStored(filename)
+	return err == nil && isSealed(content)
+}
+
+// keyID returns the ID of the encrypted key of a sealed password file.
+func keyID(encryptedKey string) string {
+	sum := sha256.Sum256([]byte(encryptedKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// registerSealKey remembers the key of the sealed password file read, and the content to authenticate.
+func registerSealKey(filename string, doc *document) error {
+	if doc.Seal == nil {
+		if len(doc.Entries) > 0 && doc.Entries[0].Sealed != "" {
+			slog.Warn("sealed password file is in an old format which is not authenticated, it is upgraded when written", "file", filename)
+		}
+		return nil
+	}
+
+	mac, err := base64.StdEncoding.DecodeString(doc.Seal.MAC)
+	if err != nil {
+		return fmt.Errorf("invalid seal header: %w", err)
+	}
+	unauthenticated := *doc
+	header := *doc.Seal
+	header.MAC = ""
+	unauthenticated.Seal = &header
+	content, err := json.Marshal(unauthenticated)
+	if err != nil {
+		return fmt.Errorf("unable to marshal to JSON: %w", err)
+	}
+
+	id := keyID(doc.Seal.Key)
+	k := sealKeys[id]
+	if k == nil {
+		k = &sealKey{}
+		sealKeys[id] = k
+	}
+	k.header = *doc.Seal
+	k.content = content
+	k.mac = mac
+	if k.key != nil {
+		return k.authenticate()
+	}
+	return nil
+}
+
+// unlock decrypts the key with the backend, unless already decrypted, and authenticates the file content.
+func (k *sealKey) unlock(backend Backend) error {
+	if k.key == nil {
+		encrypted, err := base64.StdEncoding.DecodeString(k.header.Key)
+		if err != nil {
+			return fmt.Errorf("invalid seal header: %w", err)
+		}
+		key, err := decryptBytes(backend, encrypted)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt key of sealed password file: %w", err)
+		}
+		if len(key) != 64 {
+			return fmt.Errorf("invalid key of sealed password file")
+		}
+		k.key = key
+	}
+	return k.authenticate()
+}
+
+// authenticate checks the content read against its authentication code, if not already checked.
+func (k *sealKey) authenticate() error {
+	if k.content == nil {
+		return nil
+	}
+	if !hmac.Equal(k.sum(k.content), k.mac) {
+		return ErrSealedTampered
+	}
+	k.content = nil
+	return nil
+}
+
+// sum returns the HMAC-SHA256 of content with the second half of the key.
+func (k *sealKey) sum(content []byte) []byte {
+	mac := hmac.New(sha256.New, k.key[32:])
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// aead returns AES-256-GCM with the first half of the key.
+func (k *sealKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKeyFor returns the unlocked key to write the document to a sealed password file with: the key in its seal
+// header if it has been read from one, otherwise a new key, encrypted with DefaultBackend, which is set in doc.
+func sealKeyFor(doc *document) (*sealKey, error) {
+	if doc.Seal != nil {
+		if k := sealKeys[keyID(doc.Seal.Key)]; k != nil {
+			if err := k.unlock(DefaultBackend); err != nil {
+				return nil, err
+			}
+			return k, nil
+		}
+	}
+
+	key := make([]byte, 64)
+	if _, err := cryptorand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to generate key: %w", err)
+	}
+	encrypted, err := encryptBytes(DefaultBackend, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt key of sealed password file: %w", err)
+	}
+	k := &sealKey{header: sealHeader{Format: sealFormat, Key: base64.StdEncoding.EncodeToString(encrypted)}, key: key}
+	sealKeys[keyID(k.header.Key)] = k
+	doc.Seal = &sealHeader{Format: sealFormat, Key: k.header.Key}
+	return k, nil
+}
+
+// marshalSealed formats the sealed entries and the description of the document as the content of a sealed
+// password file with the key, authenticated.
+func marshalSealed(data []PasswordEntry, doc *document, k *sealKey) ([]byte, error) {
+	if data == nil {
+		data = []PasswordEntry{}
+	}
+	description, err := sealDescription(k, doc)
+	if err != nil {
+		return nil, err
+	}
+	sealed := document{Version: schemaVersion, Seal: &sealHeader{Format: sealFormat, Key: k.header.Key, Description: description}, Entries: data}
+	content, err := json.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Seal.MAC = base64.StdEncoding.EncodeToString(k.sum(content))
+	return json.Marshal(sealed)
+}
+
+// descriptionData is the additional data authenticated with the sealed description, so that it cannot be swapped
+// with the secrets of an entry.
+var descriptionData = []byte("description")
+
+// sealDescription returns the description of the document sealed with the key, or an empty string if it has none.
+func sealDescription(k *sealKey, doc *document) (string, error) {
+	if doc.Description == "" && doc.Seal != nil && doc.Seal.Key == k.header.Key {
+		return doc.Seal.Description, nil
+	}
+	description, err := doc.description()
+	if err != nil || description == "" {
+		return "", err
+	}
+
+	aead, err := k.aead()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := cryptorand.Read(nonce); err != nil {
+		return "", fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(description), descriptionData)), nil
+}
+
+// unsealDescription returns the description sealed in the seal header, decrypting the key with the backend.
+func unsealDescription(backend Backend, header *sealHeader) (string, error) {
+	k := sealKeys[keyID(header.Key)]
+	if k == nil {
+		return "", fmt.Errorf("unknown key of sealed description")
+	}
+	if err := k.unlock(backend); err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(header.Description)
+	if err != nil {
+		return "", fmt.Errorf("invalid sealed description: %w", err)
+	}
+	aead, err := k.aead()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid sealed description")
+	}
+	description, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], descriptionData)
+	if err != nil {
+		return "", fmt.Errorf("unable to unseal description: %w", ErrSealedTampered)
+	}
+	return string(description), nil
+}
+
+// Unseal decrypts the secrets of an entry read from a sealed password file. Entries which are not sealed are
+// left as they are.
+func Unseal(entry *PasswordEntry) error {
+	return unsealWith(DefaultBackend, entry)
+}
+
+func unsealWith(backend Backend, entry *PasswordEntry) error {
+	if entry.Sealed == "" {
+		return nil
+	}
+
+	var output []byte
+	if rest, ok := strings.CutPrefix(entry.Sealed, sealedPrefix); ok {
+		id, encoded, _ := strings.Cut(rest, ":")
+		k := sealKeys[id]
+		if k == nil {
+			return fmt.Errorf("unknown key of sealed secrets of %s", entry.Name)
+		}
+		if err := k.unlock(backend); err != nil {
+			return err
+		}
+		sealed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid sealed secrets of %s: %w", entry.Name, err)
+		}
+		aead, err := k.aead()
+		if err != nil {
+			return err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("invalid sealed secrets of %s", entry.Name)
+		}
+		// The ID is authenticated, so that secrets cannot be swapped between entries
+		output, err = aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(entry.ID))
+		if err != nil {
+			return fmt.Errorf("unable to unseal secrets of %s: %w", entry.Name, ErrSealedTampered)
+		}
+	} else {
+		sealed, err := base64.StdEncoding.DecodeString(entry.Sealed)
+		if err != nil {
+			return fmt.Errorf("invalid sealed secrets of %s: %w", entry.Name, err)
+		}
+		output, err = decryptBytes(backend, sealed)
+		if err != nil {
+			return fmt.Errorf("unable to unseal secrets of %s: %w", entry.Name, err)
+		}
+	}
+
+	var secrets sealedSecrets
+	if err := json.Unmarshal(output, &secrets); err != nil {
+		return fmt.Errorf("invalid sealed secrets of %s: %w", entry.Name, err)
+	}
+	entry.Password = secrets.Password
+	entry.Accounts = secrets.Accounts
+	entry.TOTP = secrets.TOTP
+	entry.PrivateKey = secrets.PrivateKey
+	entry.RecoveryPhrase = secrets.RecoveryPhrase
+	// Entries sealed before notes were sealed keep their notes in plaintext
+	if secrets.Notes != "" {
+		entry.Notes = secrets.Notes
+	}
+	entry.Sealed = ""
+	return nil
+}
+
+// seal encrypts the secrets of an entry with the key, unless it is already sealed with it and has no notes in
+// plaintext.
+func seal(k *sealKey, entry *PasswordEntry) error {
+	if strings.HasPrefix(entry.Sealed, sealedPrefix+keyID(k.header.Key)+":") && entry.Notes == "" {
+		return nil
+	}
+	if err := Unseal(entry); err != nil {
+		return err
+	}
+
+	secrets, err := json.Marshal(sealedSecrets{
+		Password:       entry.Password,
+		Accounts:       entry.Accounts,
+		TOTP:           entry.TOTP,
+		PrivateKey:     entry.PrivateKey,
+		RecoveryPhrase: entry.RecoveryPhrase,
+		Notes:          entry.Notes,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal to JSON: %w", err)
+	}
+
+	aead, err := k.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := cryptorand.Read(nonce); err != nil {
+		return fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, secrets, []byte(entry.ID))
+	entry.Sealed = sealedPrefix + keyID(k.header.Key) + ":" + base64.StdEncoding.EncodeToString(sealed)
+	entry.Password = ""
+	entry.Accounts = nil
+	entry.TOTP = nil
+	entry.PrivateKey = ""
+	entry.RecoveryPhrase = ""
+	entry.Notes = ""
+	return nil
+}
+
+// decryptBytes decrypts data with the backend, through a temporary file.
+func decryptBytes(backend Backend, data []byte) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "gopw-sealed*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	tmpFilename := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpFilename) }()
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write temporary file: %w", err)
+	}
+
+	return backend.Decrypt(tmpFilename)
+}
+
+// encryptBytes encrypts data with the backend, through a temporary file.
+func encryptBytes(backend Backend, data []byte) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "gopw-sealed*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	tmpFilename := tmpFile.Name()
+	_ = tmpFile.Close()
+	defer func() { _ = os.Remove(tmpFilename) }()
+
+	if err := backend.Encrypt(tmpFilename, data); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmpFilename)
+}
+
+// readUnsealed reads the password file like read, with all entries unsealed.
+func readUnsealed(filename string) ([]PasswordEntry, error) {
+	data, err := read(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := unsealAll(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// unsealAll unseals all entries in place.
+func unsealAll(data []PasswordEntry) error {
+	for i := range data {
+		if err := Unseal(&data[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverSealKey remembers the key of a corrupted sealed password file, so that its entries can be unsealed. The
+// content cannot be authenticated, since it is not complete.
+func recoverSealKey(header *sealHeader) {
+	id := keyID(header.Key)
+	k := sealKeys[id]
+	if k == nil {
+		k = &sealKey{header: *header}
+		sealKeys[id] = k
+	}
+	k.content = nil
+}
@@ -0,0 +1,132 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSealedRoundTrip(t *testing.T) {
+	filename := newTestFile(t)
+	Seal = true
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	entries := []PasswordEntry{
+		{Name: "example", Username: "alice", Password: "secret1", Notes: "hint1", TOTP: &TOTP{Secret: "GEZDGNBV"}},
+		{Name: "other", Username: "bob", Password: "secret2", Accounts: []Account{{Username: "carol", Password: "secret3"}}},
+	}
+	for _, entry := range entries {
+		if err := Add(filename, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Seal = false
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSealed(content) {
+		t.Fatalf("not sealed: %s", content)
+	}
+	for _, secret := range []string{"secret1", "secret2", "secret3", "hint1", "GEZDGNBV"} {
+		if bytes.Contains(content, []byte(secret)) {
+			t.Errorf("%s in plaintext in the file", secret)
+		}
+	}
+
+	// Names are listed without decrypting the key
+	sealKeys = map[string]*sealKey{}
+	listed, err := List(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Name != "example" || listed[0].Username != "alice" || listed[0].Password != "" {
+		t.Errorf("listed %+v", listed)
+	}
+
+	sealKeys = map[string]*sealKey{}
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret1" || entry.Notes != "hint1" || entry.TOTP == nil || entry.TOTP.Secret != "GEZDGNBV" {
+		t.Errorf("got %+v", entry)
+	}
+
+	// A sealed file stays sealed when written without Seal
+	if err := Modify(filename, "other", func(entry *PasswordEntry) error {
+		entry.Password = "secret4"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSealed(content) || bytes.Contains(content, []byte("secret4")) {
+		t.Errorf("not sealed after modifying: %s", content)
+	}
+	sealKeys = map[string]*sealKey{}
+	entry, err = Get(filename, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret4" || len(entry.Accounts) != 1 || entry.Accounts[0].Password != "secret3" {
+		t.Errorf("got %+v", entry)
+	}
+}
+
+func TestSealedTampered(t *testing.T) {
+	tamperings := map[string]func(doc *document){
+		"username": func(doc *document) {
+			doc.Entries[0].Username = "mallory"
+		},
+		"swapped secrets": func(doc *document) {
+			doc.Entries[0].Sealed, doc.Entries[1].Sealed = doc.Entries[1].Sealed, doc.Entries[0].Sealed
+		},
+		"removed entry": func(doc *document) {
+			doc.Entries = doc.Entries[1:]
+		},
+	}
+	for name, tamper := range tamperings {
+		t.Run(name, func(t *testing.T) {
+			filename := newTestFile(t)
+			Seal = true
+			if err := Init(filename); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"example", "other"} {
+				if err := Add(filename, PasswordEntry{Name: name, Username: "alice", Password: "secret"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc document
+			if err := json.Unmarshal(content, &doc); err != nil {
+				t.Fatal(err)
+			}
+			tamper(&doc)
+			content, err = json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filename, content, FileMode); err != nil {
+				t.Fatal(err)
+			}
+
+			sealKeys = map[string]*sealKey{}
+			if _, err := Get(filename, "other"); !errors.Is(err, ErrSealedTampered) {
+				t.Errorf("expected ErrSealedTampered, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pw
+
+import "fmt"
+
+// maxSequenceAttempts is the number of passwords GenerateNoSequences generates before giving up.
+const maxSequenceAttempts = 100
+
+// sequenceLength is the length of runs of sequential or repeated characters which GenerateNoSequences avoids.
+const sequenceLength = 3
+
+// hasSequence reports whether the password contains a run of n characters which are the same, like "aaa",
+// or consecutive, like "abc" or "321".
+func hasSequence(password string, n int) bool {
+	chars := []rune(password)
+	return runLength(chars, 0) >= n || runLength(chars, 1) >= n || runLength(chars, -1) >= n
+}
+
+// runLength returns the length of the longest run of characters in which each character is the previous one
+// plus step.
+func runLength(chars []rune, step rune) int {
+	if len(chars) == 0 {
+		return 0
+	}
+	longest, current := 1, 1
+	for i := 1; i < len(chars); i++ {
+		if chars[i] == chars[i-1]+step {
+			current++
+		} else {
+			current = 1
+		}
+		longest = max(longest, current)
+	}
+	return longest
+}
+
+// GenerateNoSequences generates a random password like GeneratePassword, which does not contain three or more
+// sequential or repeated characters. It fails if no such password is generated within a number of attempts.
+func GenerateNoSequences(length int, charset string) (string, error) {
+	for i := 0; i < maxSequenceAttempts; i++ {
+		password, err := GeneratePassword(length, charset)
+		if err != nil {
+			return "", err
+		}
+		if !hasSequence(password, sequenceLength) {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("unable to generate a password without sequential or repeated characters in %d attempts",
+		maxSequenceAttempts)
+}
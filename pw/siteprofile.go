@@ -0,0 +1,89 @@
+package pw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrSiteProfileNotFound = errors.New("site profile not found")
+
+// SiteProfile is the password rules of a site, which generated passwords can be checked against.
+type SiteProfile struct {
+	// MinLength is the minimum password length, if not zero.
+	MinLength int `json:"minLength,omitempty"`
+	// MaxLength is the maximum password length, if not zero.
+	MaxLength int `json:"maxLength,omitempty"`
+	// Allowed is the characters allowed in passwords, if not empty.
+	Allowed string `json:"allowed,omitempty"`
+}
+
+// Check checks that the password complies with the site profile, and returns an error describing the first
+// violated rule.
+func (p SiteProfile) Check(password string) error {
+	length := len([]rune(password))
+	if p.MinLength > 0 && length < p.MinLength {
+		return fmt.Errorf("shorter than %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("longer than %d characters", p.MaxLength)
+	}
+	if p.Allowed != "" {
+		for _, r := range password {
+			if !strings.ContainsRune(p.Allowed, r) {
+				return fmt.Errorf("contains character %q not allowed", r)
+			}
+		}
+	}
+	return nil
+}
+
+// Fit returns the length and charset to generate passwords complying with the site profile with: the length
+// limited to MinLength and MaxLength, and the characters of charset which are allowed.
+func (p SiteProfile) Fit(length int, charset string) (int, string) {
+	if p.MinLength > 0 && length < p.MinLength {
+		length = p.MinLength
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		length = p.MaxLength
+	}
+	if p.Allowed != "" {
+		charset = FilterCharset(charset, p.Allowed)
+	}
+	return length, charset
+}
+
+// FitPolicy returns a copy of the policy to generate passwords complying with the site profile with, like Fit.
+func (p SiteProfile) FitPolicy(policy Policy) Policy {
+	length, charset := p.Fit(policy.length(), policy.Charset)
+	policy.Charset = charset
+	if policy.MinLength > length {
+		policy.MinLength = length
+	}
+	if policy.MaxLength > 0 || length > policy.MinLength {
+		policy.MaxLength = length
+	}
+	return policy
+}
+
+// LoadSiteProfile loads the site profile with the given name from a JSON config file, which has an object with
+// site profiles by name, e.g. {"github": {"minLength": 8, "maxLength": 72}}.
+func LoadSiteProfile(configFile string, name string) (*SiteProfile, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read site profiles: %w", err)
+	}
+
+	var profiles map[string]SiteProfile
+	if err := json.Unmarshal(content, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid site profiles in %s: %w", configFile, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSiteProfileNotFound, name)
+	}
+	return &profile, nil
+}
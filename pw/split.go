@@ -0,0 +1,227 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Splits maps password files to two share files. A split password file is not stored itself, instead the
+// encrypted file is split into the two shares with XOR secret sharing: the first share is a random pad, and
+// the second is the encrypted file XORed with the pad. Neither share alone reveals anything, both are needed
+// to read the password file.
+var Splits = map[string][2]string{}
+
+var ErrShareMissing = errors.New("share of split password file missing")
+
+// splitExists reports whether the password file is split and any of its shares exists.
+func splitExists(filename string) bool {
+	shares, ok := Splits[filename]
+	if !ok {
+		return false
+	}
+	for _, share := range shares {
+		if _, err := os.Stat(share); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// joinShares joins the shares into a temporary file with the encrypted password file, and returns its name.
+// The caller must remove the temporary file.
+func joinShares(shares [2]string) (string, error) {
+	joined, err := joinedContent(shares)
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(shares[0]), filepath.Base(shares[0])+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	_, err = tmpFile.Write(joined)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("unable to write temporary file: %w", err)
+	}
+	slog.Debug("joined shares", "shares", shares[:], "file", tmpFile.Name())
+	return tmpFile.Name(), nil
+}
+
+// joinedContent returns the encrypted password file joined from the shares.
+func joinedContent(shares [2]string) ([]byte, error) {
+	var contents [2][]byte
+	missing := 0
+	for i, share := range shares {
+		fileInfo, err := os.Stat(share)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPermissions(share, fileInfo); err != nil {
+			return nil, err
+		}
+		contents[i], err = os.ReadFile(share)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case missing == len(shares):
+		return nil, ErrPwFileNotFound
+	case contents[0] == nil:
+		return nil, fmt.Errorf("%w: %s", ErrShareMissing, shares[0])
+	case contents[1] == nil:
+		return nil, fmt.Errorf("%w: %s", ErrShareMissing, shares[1])
+	case len(contents[0]) != len(contents[1]):
+		return nil, fmt.Errorf("shares %s and %s differ in length", shares[0], shares[1])
+	}
+	if previousExists(shares) {
+		slog.Warn("writing shares was interrupted, the shares may not match, the previous shares are kept",
+			"previous", []string{previousShare(shares[0]), previousShare(shares[1])})
+	}
+
+	joined := make([]byte, len(contents[0]))
+	for i := range joined {
+		joined[i] = contents[0][i] ^ contents[1][i]
+	}
+	return joined, nil
+}
+
+// readStored returns the content of the password file as stored, joined from its shares if it is split.
+func readStored(filename string) ([]byte, error) {
+	if shares, ok := Splits[filename]; ok {
+		return joinedContent(shares)
+	}
+	return os.ReadFile(filename)
+}
+
+// writeShares splits the encrypted file into the shares, keeping backups of the previous shares if Backup is set.
+// Since a new pad is generated every time, the shares must be replaced together: both are written to temporary
+// files first, and the previous shares are kept until both are replaced, and restored if either fails.
+func writeShares(encryptedFilename string, shares [2]string) error {
+	encrypted, err := os.ReadFile(encryptedFilename)
+	if err != nil {
+		return err
+	}
+
+	pad := make([]byte, len(encrypted))
+	if _, err := cryptorand.Read(pad); err != nil {
+		return fmt.Errorf("unable to generate pad: %w", err)
+	}
+	for i := range encrypted {
+		encrypted[i] ^= pad[i]
+	}
+
+	var tmpFilenames [2]string
+	defer func() {
+		for _, tmpFilename := range tmpFilenames {
+			if tmpFilename != "" {
+				_ = os.Remove(tmpFilename)
+			}
+		}
+	}()
+	for i, content := range [2][]byte{pad, encrypted} {
+		tmpFilenames[i], err = writeShareTemp(shares[i], content)
+		if err != nil {
+			return err
+		}
+	}
+
+	if Backup {
+		for _, share := range shares {
+			if _, err := backup(share); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The shares have just been read, so any previous versions kept from an interrupted write are stale
+	removePrevious(shares)
+	for _, share := range shares {
+		if err := os.Link(share, previousShare(share)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			removePrevious(shares)
+			return fmt.Errorf("unable to keep share %s: %w", share, err)
+		}
+	}
+	for i, share := range shares {
+		if err := os.Rename(tmpFilenames[i], share); err != nil {
+			restorePrevious(shares[:i])
+			removePrevious(shares)
+			return fmt.Errorf("unable to write share %s: %w", share, err)
+		}
+	}
+	removePrevious(shares)
+	slog.Debug("wrote shares", "shares", shares[:])
+	return nil
+}
+
+// writeShareTemp writes the content of the share to a new temporary file next to it, and returns its name.
+func writeShareTemp(share string, content []byte) (string, error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(share), filepath.Base(share)+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("unable to write share %s: %w", share, err)
+	}
+	tmpFilename := tmpFile.Name()
+
+	_, err = tmpFile.Write(content)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFilename, FileMode)
+	}
+	if err != nil {
+		_ = os.Remove(tmpFilename)
+		return "", fmt.Errorf("unable to write share %s: %w", share, err)
+	}
+	return tmpFilename, nil
+}
+
+// previousShare returns the name the previous version of the share is kept as while the shares are replaced.
+func previousShare(share string) string {
+	return share + ".prev"
+}
+
+// previousExists reports whether the previous versions of the shares are kept, which means that replacing them
+// was interrupted.
+func previousExists(shares [2]string) bool {
+	for _, share := range shares {
+		if _, err := os.Stat(previousShare(share)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// restorePrevious restores the previous versions of the shares which have been replaced, or removes them if there
+// were no previous versions.
+func restorePrevious(shares []string) {
+	for _, share := range shares {
+		err := os.Rename(previousShare(share), share)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = os.Remove(share)
+		}
+		if err != nil {
+			slog.Warn("unable to restore share", "share", share, "previous", previousShare(share), "error", err)
+		}
+	}
+}
+
+// removePrevious removes the previous versions of the shares.
+func removePrevious(shares [2]string) {
+	for _, share := range shares {
+		_ = os.Remove(previousShare(share))
+	}
+}
@@ -0,0 +1,133 @@
+package pw
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitRoundTrip(t *testing.T) {
+	filename := newTestFile(t)
+	dir := filepath.Dir(filename)
+	shares := [2]string{filepath.Join(dir, "share1"), filepath.Join(dir, "share2")}
+	Splits[filename] = shares
+	t.Cleanup(func() { delete(Splits, filename) })
+
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("password file stored itself: %v", err)
+	}
+
+	var contents [2][]byte
+	for i, share := range shares {
+		var err error
+		contents[i], err = os.ReadFile(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(previousShare(share)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("previous share of %s left behind: %v", share, err)
+		}
+	}
+	if len(contents[0]) != len(contents[1]) {
+		t.Fatalf("shares differ in length: %d and %d", len(contents[0]), len(contents[1]))
+	}
+	joined := make([]byte, len(contents[0]))
+	for i := range joined {
+		joined[i] = contents[0][i] ^ contents[1][i]
+	}
+	if !bytes.HasPrefix(joined, testMagic) {
+		t.Errorf("shares do not join to the encrypted file: %q", joined)
+	}
+	for i, content := range contents {
+		if bytes.HasPrefix(content, testMagic) {
+			t.Errorf("share %d is the encrypted file", i+1)
+		}
+	}
+
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret" {
+		t.Errorf("got %+v", entry)
+	}
+
+	// A new pad is generated every time the shares are written
+	if err := Add(filename, PasswordEntry{Name: "other", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	pad, err := os.ReadFile(shares[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(pad, contents[0][:len(testMagic)]) {
+		t.Error("the pad was reused")
+	}
+	if count, err := Count(filename); err != nil || count != 2 {
+		t.Errorf("counted %d: %v", count, err)
+	}
+
+	if err := os.Remove(shares[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(filename, "example"); !errors.Is(err, ErrShareMissing) {
+		t.Errorf("expected ErrShareMissing, got %v", err)
+	}
+}
+
+func TestSplitInterruptedWrite(t *testing.T) {
+	filename := newTestFile(t)
+	dir := filepath.Dir(filename)
+	shares := [2]string{filepath.Join(dir, "share1"), filepath.Join(dir, "share2")}
+	Splits[filename] = shares
+	t.Cleanup(func() { delete(Splits, filename) })
+
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(filename, PasswordEntry{Name: "example", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupted after replacing only the first share, which leaves the previous shares as a matching pair
+	for _, share := range shares {
+		if err := os.Link(share, previousShare(share)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pad, err := os.ReadFile(shares[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaced := filepath.Join(dir, "replaced")
+	if err := os.WriteFile(replaced, make([]byte, len(pad)), FileMode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replaced, shares[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(filename, "example"); err == nil {
+		t.Error("expected an error reading mismatched shares")
+	}
+
+	for _, share := range shares {
+		if err := os.Rename(previousShare(share), share); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry, err := Get(filename, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "secret" {
+		t.Errorf("got %+v after restoring the previous shares", entry)
+	}
+}
@@ -0,0 +1,39 @@
+package pw
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// KeyTypeSSH is the KeyType of entries holding an SSH private key.
+const KeyTypeSSH = "ssh"
+
+var ErrNoPrivateKey = errors.New("entry has no private key")
+
+// AddSSHKey adds a new entry holding the SSH private key, which must be PEM encoded.
+func AddSSHKey(filename string, name string, key []byte) error {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return fmt.Errorf("the key is not PEM encoded")
+	}
+
+	return Add(filename, PasswordEntry{
+		Name:       name,
+		KeyType:    KeyTypeSSH,
+		PrivateKey: string(pem.EncodeToMemory(block)),
+	})
+}
+
+// GetSSHKey fetches the SSH private key of the entry with the given name or alias.
+func GetSSHKey(filename string, name string) ([]byte, error) {
+	entry, err := Get(filename, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.KeyType != KeyTypeSSH || entry.PrivateKey == "" {
+		return nil, ErrNoPrivateKey
+	}
+	return []byte(entry.PrivateKey), nil
+}
@@ -0,0 +1,157 @@
+package pw
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Pool sizes of the character classes, for estimating the strength of a password.
+const (
+	lowerPoolSize  = 26
+	upperPoolSize  = 26
+	digitPoolSize  = 10
+	symbolPoolSize = 33
+)
+
+// Strength returns the entropy in bits of a random password of length characters from charset.
+func Strength(length int, charset string) float64 {
+	unique := make(map[rune]bool, len(charset))
+	for _, c := range charset {
+		unique[c] = true
+	}
+	if length <= 0 || len(unique) == 0 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(len(unique)))
+}
+
+// maxEntropyLength is the longest length LengthForEntropy returns.
+const maxEntropyLength = 1024
+
+// LengthForEntropy returns the shortest length of random passwords from charset with at least minEntropy bits,
+// as computed by Strength.
+func LengthForEntropy(minEntropy float64, charset string) (int, error) {
+	perChar := Strength(1, charset)
+	if perChar == 0 {
+		return 0, fmt.Errorf("charset must have at least two different characters")
+	}
+	length := max(int(math.Ceil(minEntropy/perChar-1e-9)), 1)
+	if length > maxEntropyLength {
+		return 0, fmt.Errorf("a password with %.0f bits of entropy would be longer than %d characters", minEntropy, maxEntropyLength)
+	}
+	return length, nil
+}
+
+// PasswordStrength estimates the entropy in bits of a password, assuming it is random with
+// characters from the full classes (lowercase, uppercase, digits and symbols) it contains.
+func PasswordStrength(password string) float64 {
+	pool := 0
+	if strings.ContainsFunc(password, classLower.contains) {
+		pool += lowerPoolSize
+	}
+	if strings.ContainsFunc(password, classUpper.contains) {
+		pool += upperPoolSize
+	}
+	if strings.ContainsFunc(password, classDigit.contains) {
+		pool += digitPoolSize
+	}
+	if strings.ContainsFunc(password, classSymbol.contains) {
+		pool += symbolPoolSize
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(len([]rune(password))) * math.Log2(float64(pool))
+}
+
+// WeakPasswords returns the names of the entries with a password strength, as estimated by PasswordStrength,
+// below minEntropy bits, sorted. Entries holding a private key are skipped.
+func WeakPasswords(filename string, minEntropy float64) ([]string, error) {
+	weak, err := WeakPasswordStrengths(filename, minEntropy)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, password := range weak {
+		names = append(names, password.Name)
+	}
+	return names, nil
+}
+
+// WeakPassword is a password with a strength below the threshold of WeakPasswordStrengths.
+type WeakPassword struct {
+	// Name is the name of the entry.
+	Name string
+	// Entropy is the strength in bits, as estimated by PasswordStrength.
+	Entropy float64
+}
+
+// WeakPasswordStrengths is like WeakPasswords, but also returns the strength of each password.
+func WeakPasswordStrengths(filename string, minEntropy float64) ([]WeakPassword, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var weak []WeakPassword
+	for _, entry := range weakEntries(data, minEntropy) {
+		weak = append(weak, WeakPassword{Name: entry.Name, Entropy: PasswordStrength(entry.Password)})
+	}
+	return weak, nil
+}
+
+// weakEntries returns the entries with a password strength below minEntropy bits, sorted by name.
+func weakEntries(data []PasswordEntry, minEntropy float64) []PasswordEntry {
+	var weak []PasswordEntry
+	for _, entry := range data {
+		if entry.KeyType == "" && PasswordStrength(entry.Password) < minEntropy {
+			weak = append(weak, entry)
+		}
+	}
+	sort.Slice(weak, func(i, j int) bool {
+		return weak[i].Name < weak[j].Name
+	})
+	return weak
+}
+
+// PasswordInfo describes the style of a password, without revealing it.
+type PasswordInfo struct {
+	// Classes are the names of the character classes the password contains.
+	Classes []string
+	// Length is the number of characters.
+	Length int
+	// Entropy is the strength in bits, as estimated by PasswordStrength.
+	Entropy float64
+}
+
+// AnalyzePassword returns the character classes, length and estimated strength of a password.
+func AnalyzePassword(password string) PasswordInfo {
+	info := PasswordInfo{
+		Length:  len([]rune(password)),
+		Entropy: PasswordStrength(password),
+	}
+	for _, class := range []characterClass{classLower, classUpper, classDigit, classSymbol} {
+		if strings.ContainsFunc(password, class.contains) {
+			info.Classes = append(info.Classes, class.name)
+		}
+	}
+	return info
+}
+
+// Inspect returns the character classes, length and estimated strength of the password of the entry with the
+// given name.
+func Inspect(filename string, name string) (*PasswordInfo, error) {
+	entry, err := Get(filename, name)
+	if err != nil {
+		return nil, err
+	}
+	info := AnalyzePassword(entry.Password)
+	return &info, nil
+}
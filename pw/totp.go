@@ -0,0 +1,135 @@
+package pw
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoTOTP = errors.New("entry has no TOTP secret")
+
+// TOTP is the configuration of time-based one-time passwords (RFC 6238) for an entry.
+type TOTP struct {
+	// Secret is the base32 encoded shared secret.
+	Secret string `json:"secret"`
+	// Algorithm is the hash algorithm, SHA1 (default), SHA256 or SHA512.
+	Algorithm string `json:"algorithm,omitempty"`
+	// Digits is the number of digits in a code, default 6.
+	Digits int `json:"digits,omitempty"`
+	// Period is the validity period of a code in seconds, default 30.
+	Period int `json:"period,omitempty"`
+}
+
+// decodeSecret decodes the base32 secret, with or without padding and spaces.
+func (t TOTP) decodeSecret() ([]byte, error) {
+	secret := strings.ToUpper(strings.ReplaceAll(t.Secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// Code returns the one-time password valid at the time at.
+func (t TOTP) Code(at time.Time) (string, error) {
+	key, err := t.decodeSecret()
+	if err != nil {
+		return "", err
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToUpper(t.Algorithm) {
+	case "", "SHA1":
+		newHash = sha1.New
+	case "SHA256":
+		newHash = sha256.New
+	case "SHA512":
+		newHash = sha512.New
+	default:
+		return "", fmt.Errorf("unsupported TOTP algorithm: %s", t.Algorithm)
+	}
+	digits := t.Digits
+	if digits == 0 {
+		digits = 6
+	}
+	period := t.Period
+	if period == 0 {
+		period = 30
+	}
+
+	mac := hmac.New(newHash, key)
+	_ = binary.Write(mac, binary.BigEndian, uint64(at.Unix()/int64(period)))
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	modulus := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, uint64(value)%modulus), nil
+}
+
+// SetTOTP sets the TOTP configuration of the password entry with the given name, or removes it if totp is nil.
+func SetTOTP(filename string, name string, totp *TOTP) error {
+	if totp != nil {
+		if _, err := totp.decodeSecret(); err != nil {
+			return err
+		}
+	}
+
+	return Modify(filename, name, func(entry *PasswordEntry) error {
+		entry.TOTP = totp
+		return nil
+	})
+}
+
+// OTPAuthURI builds an otpauth:// URI for the TOTP secret of the entry, for registering it in an
+// authenticator app. The issuer defaults to the entry name.
+func OTPAuthURI(entry PasswordEntry, issuer string) (string, error) {
+	if entry.TOTP == nil || entry.TOTP.Secret == "" {
+		return "", ErrNoTOTP
+	}
+
+	if issuer == "" {
+		issuer = entry.Name
+	}
+	account := entry.Username
+	if account == "" {
+		account = entry.Name
+	}
+
+	query := url.Values{}
+	query.Set("secret", strings.ToUpper(strings.ReplaceAll(strings.TrimRight(entry.TOTP.Secret, "="), " ", "")))
+	query.Set("issuer", issuer)
+	if entry.TOTP.Algorithm != "" {
+		query.Set("algorithm", entry.TOTP.Algorithm)
+	}
+	if entry.TOTP.Digits != 0 {
+		query.Set("digits", strconv.Itoa(entry.TOTP.Digits))
+	}
+	if entry.TOTP.Period != 0 {
+		query.Set("period", strconv.Itoa(entry.TOTP.Period))
+	}
+
+	// Spaces are encoded as %20 rather than +, as required by RFC 3986
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	return "otpauth://totp/" + labelEscape(issuer) + ":" + labelEscape(account) + "?" + rawQuery, nil
+}
+
+// labelEscape escapes s for the label of an otpauth:// URI. Colons are escaped as well, since url.PathEscape
+// leaves them as is, and the colon separates the issuer from the account in the label.
+func labelEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), ":", "%3A")
+}
@@ -0,0 +1,112 @@
+package pw
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The test vectors of RFC 6238, appendix B
+	secrets := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		code      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, test := range tests {
+		totp := TOTP{
+			Secret:    base32.StdEncoding.EncodeToString([]byte(secrets[test.algorithm])),
+			Algorithm: test.algorithm,
+			Digits:    8,
+		}
+		code, err := totp.Code(time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("code at %d with %s is %s, expected %s", test.unix, test.algorithm, code, test.code)
+		}
+	}
+}
+
+func TestTOTPCodeDefaults(t *testing.T) {
+	// Lowercase and unpadded with spaces, 6 digits and 30 seconds by default
+	totp := TOTP{Secret: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq"}
+	code, err := totp.Code(time.Unix(59, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Errorf("code is %s, expected 287082", code)
+	}
+
+	if _, err := (TOTP{Secret: "not base32!"}).Code(time.Now()); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+	if _, err := (TOTP{Secret: "GEZDGNBV", Algorithm: "MD5"}).Code(time.Now()); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestOTPAuthURI(t *testing.T) {
+	tests := []struct {
+		entry  PasswordEntry
+		issuer string
+		uri    string
+	}{
+		{
+			PasswordEntry{Name: "example", Username: "alice", TOTP: &TOTP{Secret: "gezdgnbv"}},
+			"",
+			"otpauth://totp/example:alice?issuer=example&secret=GEZDGNBV",
+		},
+		{
+			PasswordEntry{Name: "example", TOTP: &TOTP{Secret: "GEZDGNBV", Digits: 8, Period: 60}},
+			"Example Inc",
+			"otpauth://totp/Example%20Inc:example?digits=8&issuer=Example%20Inc&period=60&secret=GEZDGNBV",
+		},
+		{
+			// Colons in the issuer and account must not be taken as the separator of the label
+			PasswordEntry{Name: "example", Username: "alice:work/home", TOTP: &TOTP{Secret: "GEZDGNBV"}},
+			"Acme: Corp",
+			"otpauth://totp/Acme%3A%20Corp:alice%3Awork%2Fhome?issuer=Acme%3A%20Corp&secret=GEZDGNBV",
+		},
+	}
+	for _, test := range tests {
+		uri, err := OTPAuthURI(test.entry, test.issuer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri != test.uri {
+			t.Errorf("URI is %s, expected %s", uri, test.uri)
+		}
+	}
+
+	if _, err := OTPAuthURI(PasswordEntry{Name: "example"}, ""); !errors.Is(err, ErrNoTOTP) {
+		t.Errorf("expected ErrNoTOTP without TOTP, got %v", err)
+	}
+}
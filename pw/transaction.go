@@ -0,0 +1,102 @@
+package pw
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// LockTimeout is how long Transaction waits for another transaction on the same password file to finish.
+var LockTimeout = 10 * time.Second
+
+// lockPollInterval is how often to try again to lock a password file which is locked.
+const lockPollInterval = 50 * time.Millisecond
+
+var ErrLocked = errors.New("password file is locked")
+
+// Transaction reads all password entries in plaintext, passes them to fn, which may change them in place, and
+// writes them back to the file in a single write if fn returns nil. Entries without an ID, as added by fn, get
+// one. If fn returns an error, nothing is written and the error is returned.
+//
+// Concurrent transactions on the same password file, also in other processes, are serialized with a lock file
+// next to the password file. Modifying the password file in other ways does not take the lock.
+func Transaction(filename string, fn func(entries *[]PasswordEntry) error) error {
+	if len(filename) == 0 {
+		return fmt.Errorf("filename cannot be empty")
+	}
+	if variable, ok := envVariable(filename); ok {
+		return fmt.Errorf("%w: %s is read from environment variable %s", ErrReadOnly, filename, variable)
+	}
+
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, err := readDocument(filename)
+	if err != nil {
+		return err
+	}
+	data := doc.Entries
+	if err := unsealAll(data); err != nil {
+		return err
+	}
+
+	if err := fn(&data); err != nil {
+		return err
+	}
+
+	for i, entry := range data {
+		if entry.Name == "" {
+			return fmt.Errorf("entry %d has no name", i+1)
+		}
+		for _, other := range data[:i] {
+			if other.hasName(entry.Name) {
+				return fmt.Errorf("%w: %s", ErrPwAlreadyExists, entry.Name)
+			}
+		}
+		if entry.ID == "" {
+			data[i].ID, err = newID()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return write(filename, doc.with(data))
+}
+
+// lockFile locks the password file by creating a lock file next to it, waiting up to LockTimeout if it is
+// already locked, and returns a function removing the lock file.
+func lockFile(filename string) (func(), error) {
+	lockFilename := filename + ".lock"
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		file, err := os.OpenFile(lockFilename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, _ = fmt.Fprintf(file, "%d\n", os.Getpid())
+			_ = file.Close()
+			slog.Debug("locked password file", "file", filename)
+			return func() {
+				if err := os.Remove(lockFilename); err != nil {
+					slog.Warn("unable to remove lock file", "file", lockFilename, "error", err)
+				}
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("unable to lock password file: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w, remove %s if no other gopw is running", ErrLocked, lockFilename)
+		}
+		select {
+		case <-Context.Done():
+			return nil, Context.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
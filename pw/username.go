@@ -0,0 +1,56 @@
+package pw
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// usernameKey returns the key to group the username by: the username lowercased if it is an email address, since
+// email addresses are in practice case-insensitive, otherwise the username as it is.
+func usernameKey(username string) string {
+	if emailPattern.MatchString(username) {
+		return strings.ToLower(username)
+	}
+	return username
+}
+
+// GroupByUsername returns the names of the entries using each username, also in additional accounts, sorted by
+// name. Email addresses are matched case-insensitively and keyed lowercased, other usernames exactly. Entries
+// without a username are left out.
+func GroupByUsername(filename string) (map[string][]string, error) {
+	if len(filename) == 0 {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	data, err := read(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, entry := range data {
+		for _, account := range entry.accounts() {
+			if account.Username == "" {
+				continue
+			}
+			key := usernameKey(account.Username)
+			if !slices.Contains(groups[key], entry.Name) {
+				groups[key] = append(groups[key], entry.Name)
+			}
+		}
+	}
+	for _, names := range groups {
+		slices.Sort(names)
+	}
+	return groups, nil
+}
+
+// ByUsername returns the names of the entries using the username, matched as by GroupByUsername.
+func ByUsername(filename string, username string) ([]string, error) {
+	groups, err := GroupByUsername(filename)
+	if err != nil {
+		return nil, err
+	}
+	return groups[usernameKey(username)], nil
+}
@@ -0,0 +1,73 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// EntryDifference is an entry which differs between a password file and a backup, with the JSON names of the
+// fields which differ.
+type EntryDifference struct {
+	Name   string
+	Fields []string
+}
+
+// VerifyBackup compares the password file with a plaintext JSON backup, as written by ExportJSONL, by entry name
+// and all fields. It returns the names of entries only in the backup (added), only in the password file (removed),
+// and the entries in both with different fields (changed), each sorted by name. The backup matches if all are
+// empty.
+func VerifyBackup(filename string, backup io.Reader) (added []string, removed []string, changed []EntryDifference, err error) {
+	if len(filename) == 0 {
+		return nil, nil, nil, fmt.Errorf("filename cannot be empty")
+	}
+
+	backupData, err := ParseJSON(backup)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	data, err := readUnsealed(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fields := make(map[string][]string)
+	added, removed, changedNames := diffEntries(data, backupData, func(a PasswordEntry, b PasswordEntry) bool {
+		fields[a.Name] = differingFields(a, b)
+		return len(fields[a.Name]) > 0
+	})
+	for _, name := range changedNames {
+		changed = append(changed, EntryDifference{Name: name, Fields: fields[name]})
+	}
+	return added, removed, changed, nil
+}
+
+// differingFields returns the sorted JSON names of the fields which differ between the entries.
+func differingFields(a PasswordEntry, b PasswordEntry) []string {
+	fieldsA := jsonFields(a)
+	fieldsB := jsonFields(b)
+	var differing []string
+	for name, valueA := range fieldsA {
+		if valueB, found := fieldsB[name]; !found || !bytes.Equal(valueA, valueB) {
+			differing = append(differing, name)
+		}
+	}
+	for name := range fieldsB {
+		if _, found := fieldsA[name]; !found {
+			differing = append(differing, name)
+		}
+	}
+	sort.Strings(differing)
+	return differing
+}
+
+// jsonFields returns the fields of the entry as in JSON, by name.
+func jsonFields(entry PasswordEntry) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if data, err := json.Marshal(entry); err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	return fields
+}
@@ -0,0 +1,77 @@
+package pw
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDifferingFields(t *testing.T) {
+	base := PasswordEntry{ID: "1", Name: "example", Username: "alice", Password: "secret", Tags: []string{"work"}}
+	tests := []struct {
+		change func(entry *PasswordEntry)
+		fields []string
+	}{
+		{func(entry *PasswordEntry) {}, nil},
+		{func(entry *PasswordEntry) { entry.Password = "other" }, []string{"password"}},
+		{func(entry *PasswordEntry) { entry.Username = "bob"; entry.URL = "https://example.com" }, []string{"url", "username"}},
+		{func(entry *PasswordEntry) { entry.Tags = nil }, []string{"tags"}},
+		{func(entry *PasswordEntry) { entry.Tags = []string{"work", "home"} }, []string{"tags"}},
+	}
+	for _, test := range tests {
+		changed := base
+		test.change(&changed)
+		if fields := differingFields(base, changed); !slices.Equal(fields, test.fields) {
+			t.Errorf("differing fields of %+v are %v, expected %v", changed, fields, test.fields)
+		}
+	}
+}
+
+func TestVerifyBackup(t *testing.T) {
+	filename := newTestFile(t)
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := Add(filename, PasswordEntry{Name: name, Username: "alice", Password: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var exported bytes.Buffer
+	if err := ExportJSONL(filename, &exported); err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed, changed, err := VerifyBackup(filename, bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 || len(removed) != 0 || len(changed) != 0 {
+		t.Errorf("exported backup does not match: added %v, removed %v, changed %v", added, removed, changed)
+	}
+
+	entries, err := ParseJSON(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = append(entries[1:], PasswordEntry{Name: "d", Password: "secret"})
+	entries[0].Password = "other"
+	entries[0].Username = "bob"
+	var backup bytes.Buffer
+	encoder := json.NewEncoder(&backup)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	added, removed, changed, err = VerifyBackup(filename, &backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(added, []string{"d"}) || !slices.Equal(removed, []string{"a"}) ||
+		len(changed) != 1 || changed[0].Name != "b" || !slices.Equal(changed[0].Fields, []string{"password", "username"}) {
+		t.Errorf("got added %v, removed %v, changed %+v", added, removed, changed)
+	}
+}
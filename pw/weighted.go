@@ -0,0 +1,78 @@
+package pw
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// weightedClassChars are the characters of each character class for GenerateWeighted.
+var weightedClassChars = map[string]string{
+	"lower":  "abcdefghijklmnopqrstuvwxyz",
+	"upper":  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digit":  "0123456789",
+	"symbol": "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// GenerateWeighted generates a random password where each character is from a character class chosen with a
+// probability proportional to its weight in weights, keyed by lower, upper, digit and symbol. Classes without
+// a weight are not used. Both the class and the character within it are chosen with crypto/rand.
+func GenerateWeighted(length int, weights map[string]float64) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("length must be positive")
+	}
+
+	for name := range weights {
+		if _, ok := weightedClassChars[name]; !ok {
+			return "", fmt.Errorf("unknown character class: %s", name)
+		}
+	}
+
+	var classes []string
+	total := 0.0
+	for _, class := range []characterClass{classLower, classUpper, classDigit, classSymbol} {
+		weight, ok := weights[class.name]
+		if !ok {
+			continue
+		}
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return "", fmt.Errorf("invalid weight for %s: %v", class.name, weight)
+		}
+		if weight > 0 {
+			classes = append(classes, class.name)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return "", fmt.Errorf("at least one weight must be positive")
+	}
+
+	// Uniform random numbers in [0, 1) with the 53 bits of precision of a float64
+	precision := big.NewInt(1 << 53)
+	var password strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := cryptorand.Int(cryptorand.Reader, precision)
+		if err != nil {
+			return "", err
+		}
+		r := float64(n.Int64()) / float64(precision.Int64()) * total
+
+		class := classes[len(classes)-1]
+		for _, name := range classes {
+			if r < weights[name] {
+				class = name
+				break
+			}
+			r -= weights[name]
+		}
+
+		c, err := randomElement([]rune(weightedClassChars[class]))
+		if err != nil {
+			return "", err
+		}
+		password.WriteRune(c)
+	}
+	return password.String(), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/mikaelstaldal/gopw/pw"
+)
+
+// templateEntry is a password entry as seen by list templates. The password is only available if allowed.
+type templateEntry struct {
+	ID       string
+	Name     string
+	Username string
+	Aliases  []string
+	Tags     []string
+	URL      string
+
+	entry          pw.PasswordEntry
+	allowPasswords bool
+}
+
+// Password returns the password, which fails unless passwords are explicitly allowed.
+func (e templateEntry) Password() (string, error) {
+	if !e.allowPasswords {
+		return "", fmt.Errorf("passwords are not allowed in templates without -allow-passwords")
+	}
+	if err := pw.Unseal(&e.entry); err != nil {
+		return "", err
+	}
+	return e.entry.Password, nil
+}
+
+// parseTemplateFile parses a list template file.
+func parseTemplateFile(templateFile string) (*template.Template, error) {
+	content, err := os.ReadFile(templateFile)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(templateFile).Parse(string(content))
+}
+
+// renderEntries renders the entries through the template, which gets a slice of templateEntry as data.
+func renderEntries(tmpl *template.Template, entries []pw.PasswordEntry, allowPasswords bool, w io.Writer) error {
+	data := make([]templateEntry, 0, len(entries))
+	for _, entry := range entries {
+		data = append(data, templateEntry{
+			ID:             entry.ID,
+			Name:           entry.Name,
+			Username:       entry.Username,
+			Aliases:        entry.Aliases,
+			Tags:           entry.Tags,
+			URL:            entry.URL,
+			entry:          entry,
+			allowPasswords: allowPasswords,
+		})
+	}
+	return tmpl.Execute(w, data)
+}